@@ -0,0 +1,85 @@
+// -*- tab-width: 4; -*-
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+
+	"github.com/garyjg/shapepuzzle/board"
+)
+
+// analyze finds every solution of a puzzle and prints where each piece
+// goes in them, with an SVG heatmap of the cells each piece covers.
+func analyze(args []string) int {
+
+	fs := flag.NewFlagSet("analyze", flag.ContinueOnError)
+	format := fs.String("format", "table",
+		"print the analysis as a table or json")
+	svgFile := fs.String("svg", "", "write a heatmap of the pieces to this file")
+	workers := fs.Int("workers", runtime.NumCPU(),
+		"number of goroutines sharing the search")
+	logLevel := fs.String("log-level", "warn",
+		"log messages at this level or above: debug, info, warn or error")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: shapepuzzle analyze [flags] NAME|FILE")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 || (*format != "table" && *format != "json") {
+		fs.Usage()
+		return 2
+	}
+	logger, err := newLogger(*logLevel)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	p, err := loadPuzzle(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	a := board.NewAnalyzer(p.Board, p.Shapes)
+	opts := board.Options{Strategy: board.ConstrainedCell, Workers: *workers,
+		Logger: logger}
+	solutions, err := p.Board.SolveOptions(p.Shapes, opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	for sol := range solutions {
+		a.Add(sol)
+	}
+	an := a.Analysis()
+	if *format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(an)
+	} else {
+		err = an.WriteTable(os.Stdout)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if *svgFile != "" {
+		f, err := os.Create(*svgFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer f.Close()
+		if err := an.WriteSVG(f); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,356 @@
+// -*- tab-width: 4; -*-
+
+package board
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/garyjg/shapepuzzle/mask"
+	"github.com/garyjg/shapepuzzle/shape"
+)
+
+// Analysis describes where the pieces go over a set of solutions, to show
+// a puzzle designer the constraints which shape its solutions.  Solutions
+// which are the same when the board is turned or flipped over are counted
+// once, since otherwise every count would be spread evenly across the
+// symmetries of the board.  Symmetries is the number of ways the board
+// maps onto itself, including leaving it alone.
+type Analysis struct {
+	Rows       int   `json:"rows"`
+	Cols       int   `json:"cols"`
+	Solutions  int64 `json:"solutions"`
+	Symmetries int   `json:"symmetries"`
+
+	// Pieces has the statistics of each shape, in the order they were
+	// given to NewAnalyzer.
+	Pieces []PieceAnalysis `json:"pieces"`
+
+	// Pairs counts the solutions in which two shapes touch along an edge,
+	// for every pair which touches in at least one solution.
+	Pairs []PairCount `json:"pairs"`
+
+	// Fixed are the placements found in every solution.
+	Fixed []FixedPlacement `json:"fixed"`
+}
+
+// PieceAnalysis counts the solutions which cover each cell of the board
+// with a shape, and which place it in each of its Permutations.
+// Placements which wrap across the edges of a cylinder or torus are not
+// counted by orientation, since they are not a translation of one.
+type PieceAnalysis struct {
+	ID           int       `json:"id"`
+	Name         string    `json:"name,omitempty"`
+	Occupancy    [][]int64 `json:"occupancy"`
+	Orientations []int64   `json:"orientations"`
+}
+
+// PairCount is the number of solutions in which the shapes with IDs A and
+// B, with A less than B, are next to each other.
+type PairCount struct {
+	A         int   `json:"a"`
+	B         int   `json:"b"`
+	Solutions int64 `json:"solutions"`
+}
+
+// FixedPlacement is a placement of the shape with the given ID which is
+// found in every solution, as the mask of the cells it covers.
+type FixedPlacement struct {
+	ID   int       `json:"id"`
+	Mask mask.Bits `json:"mask"`
+}
+
+// Analyzer gathers an Analysis from the solutions passed to Add.
+type Analyzer struct {
+	board      Board
+	perms      [][]shape.Shape
+	index      map[int]int
+	analysis   Analysis
+	pairs      map[[2]int]int64
+	placed     map[placementKey]int64
+	positions  []shape.Shape
+	symmetries []func(mask.Bits) mask.Bits
+}
+
+// placementKey identifies a placement by its shape and its cells.
+type placementKey struct {
+	id   int
+	mask mask.Bits
+}
+
+// NewAnalyzer starts an Analysis of the solutions which fill the board
+// with the shapes.
+func NewAnalyzer(b Board, shapes []shape.Shape) *Analyzer {
+	a := &Analyzer{board: b, index: map[int]int{},
+		pairs: map[[2]int]int64{}, placed: map[placementKey]int64{},
+		symmetries: b.symmetries()}
+	a.analysis = Analysis{Rows: b.NumRows(), Cols: b.NumCols(),
+		Symmetries: len(a.symmetries) + 1}
+	for i, s := range shapes {
+		a.index[s.ID()] = i
+		a.perms = append(a.perms, s.Permutations())
+		pa := PieceAnalysis{ID: s.ID(), Name: s.Name(),
+			Occupancy:    make([][]int64, b.NumRows()),
+			Orientations: make([]int64, len(a.perms[i]))}
+		for r := range pa.Occupancy {
+			pa.Occupancy[r] = make([]int64, b.NumCols())
+		}
+		a.analysis.Pieces = append(a.analysis.Pieces, pa)
+	}
+	return a
+}
+
+// Add counts a solution, unless turning or flipping over the board makes
+// it a solution which comes before it in the order of imageKey.  Every
+// solution is passed to Add by a complete search, so each set of
+// solutions which are the same but for a symmetry of the board is counted
+// exactly once.  The placements already on the board the Analyzer was made
+// with, and holes placed as shape 0, are left out.
+func (a *Analyzer) Add(sol Board) {
+
+	placements := []shape.Shape{}
+	for _, p := range sol.placements[len(a.board.placements):] {
+		if _, ok := a.index[p.ID()]; ok && p.ID() != 0 {
+			placements = append(placements, p)
+		}
+	}
+	key := imageKey(placements, nil)
+	for _, f := range a.symmetries {
+		if imageKey(placements, f) < key {
+			return
+		}
+	}
+	a.analysis.Solutions++
+	for i, p := range placements {
+		k := a.index[p.ID()]
+		pa := &a.analysis.Pieces[k]
+		for r, c := range p.Mask().Cells() {
+			pa.Occupancy[r][c]++
+		}
+		m := p.Mask().Normalize()
+		for o, perm := range a.perms[k] {
+			if perm.Mask() == m {
+				pa.Orientations[o]++
+				break
+			}
+		}
+
+		key := placementKey{p.ID(), p.Mask()}
+		if a.placed[key] == 0 {
+			a.positions = append(a.positions, p)
+		}
+		a.placed[key]++
+
+		near := a.board.neighbors(p.Mask())
+		for _, q := range placements[i+1:] {
+			if near&q.Mask() != 0 {
+				a.pairs[[2]int{min(p.ID(), q.ID()), max(p.ID(), q.ID())}]++
+			}
+		}
+	}
+}
+
+// imageKey identifies the placements of a solution, after moving each
+// one with f if f is not nil, as their IDs and masks in order.
+func imageKey(placements []shape.Shape,
+	f func(mask.Bits) mask.Bits) string {
+	keys := make([]string, len(placements))
+	for i, p := range placements {
+		m := p.Mask()
+		if f != nil {
+			m = f(m)
+		}
+		keys[i] = fmt.Sprintf("%04d:%v", p.ID(), m)
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, " ")
+}
+
+// symmetries returns the ways of turning or flipping over the board which
+// map it onto itself, other than leaving it alone: the mirror images in
+// its middle row and column, and on a square board the reflections in its
+// diagonals and the quarter turns as well.  The filled cells and the
+// placements already on the board must map onto themselves, and a board
+// which wraps around its columns but not its rows cannot be turned.
+func (b Board) symmetries() []func(mask.Bits) mask.Bits {
+
+	rows, cols := b.NumRows(), b.NumCols()
+	flipRows := func(m mask.Bits) mask.Bits {
+		return m.ReverseRows().Translate(rows-8, 0)
+	}
+	flipCols := func(m mask.Bits) mask.Bits {
+		return m.ReverseColumns().Translate(0, cols-8)
+	}
+	all := []func(mask.Bits) mask.Bits{flipRows, flipCols,
+		func(m mask.Bits) mask.Bits { return flipRows(flipCols(m)) }}
+	if rows == cols && b.Topology() != Cylinder {
+		all = append(all, mask.Bits.Transpose)
+		for _, f := range all[:3] {
+			all = append(all, func(m mask.Bits) mask.Bits {
+				return f(m).Transpose()
+			})
+		}
+	}
+
+	base := imageKey(b.placements, nil)
+	symmetries := []func(mask.Bits) mask.Bits{}
+	for _, f := range all {
+		if f(b.Mask()) == b.Mask() && imageKey(b.placements, f) == base {
+			symmetries = append(symmetries, f)
+		}
+	}
+	return symmetries
+}
+
+// Analysis returns the statistics of the solutions added so far.  The
+// pairs are sorted by the IDs of their shapes.
+func (a *Analyzer) Analysis() Analysis {
+
+	an := a.analysis
+	an.Pairs = []PairCount{}
+	for ids, n := range a.pairs {
+		an.Pairs = append(an.Pairs, PairCount{A: ids[0], B: ids[1],
+			Solutions: n})
+	}
+	sort.Slice(an.Pairs, func(i, j int) bool {
+		p, q := an.Pairs[i], an.Pairs[j]
+		return p.A < q.A || (p.A == q.A && p.B < q.B)
+	})
+	an.Fixed = []FixedPlacement{}
+	for _, p := range a.positions {
+		if an.Solutions > 0 &&
+			a.placed[placementKey{p.ID(), p.Mask()}] == an.Solutions {
+			an.Fixed = append(an.Fixed, FixedPlacement{p.ID(), p.Mask()})
+		}
+	}
+	return an
+}
+
+// name returns the name of the shape with the given ID, or the ID itself
+// if the shape has no name.
+func (an Analysis) name(id int) string {
+	for _, pa := range an.Pieces {
+		if pa.ID == id && pa.Name != "" {
+			return pa.Name
+		}
+	}
+	return fmt.Sprint(id)
+}
+
+// WriteTable prints the Analysis as text: the orientations each piece is
+// placed in, the percentage of solutions in which it covers each cell, the
+// pairs of pieces which touch in every solution, and the placements found
+// in every solution.
+func (an Analysis) WriteTable(w io.Writer) error {
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "solutions\t%d, up to %d symmetries of the board\n",
+		an.Solutions, an.Symmetries)
+	fmt.Fprintf(tw, "piece\torientations used\tcounts\n")
+	for _, pa := range an.Pieces {
+		used, counts := 0, []string{}
+		for _, n := range pa.Orientations {
+			if n > 0 {
+				used++
+			}
+			counts = append(counts, fmt.Sprint(n))
+		}
+		fmt.Fprintf(tw, "%s\t%d of %d\t%s\n", an.name(pa.ID), used,
+			len(pa.Orientations), strings.Join(counts, " "))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	for _, pa := range an.Pieces {
+		fmt.Fprintf(w, "\npiece %s cell occupancy (%%):\n", an.name(pa.ID))
+		for _, row := range pa.Occupancy {
+			fmt.Fprint(w, "[")
+			for _, n := range row {
+				fmt.Fprintf(w, " %3.0f", an.percent(n))
+			}
+			fmt.Fprint(w, "]\n")
+		}
+	}
+
+	always := []string{}
+	for _, p := range an.Pairs {
+		if p.Solutions == an.Solutions {
+			always = append(always, an.name(p.A)+"-"+an.name(p.B))
+		}
+	}
+	fmt.Fprintf(w, "\nalways adjacent: %s\n", listOrNone(always))
+
+	fixed := []string{}
+	for _, p := range an.Fixed {
+		fixed = append(fixed, an.name(p.ID))
+	}
+	_, err := fmt.Fprintf(w, "in every solution: %s\n", listOrNone(fixed))
+	if len(an.Fixed) > 0 {
+		b := NewBoard(an.Rows, an.Cols)
+		for _, p := range an.Fixed {
+			b = b.Place(shape.FromMask(p.ID, p.Mask))
+		}
+		_, err = fmt.Fprint(w, b)
+	}
+	return err
+}
+
+// percent returns n as a percentage of the solutions.
+func (an Analysis) percent(n int64) float64 {
+	if an.Solutions == 0 {
+		return 0
+	}
+	return 100 * float64(n) / float64(an.Solutions)
+}
+
+func listOrNone(items []string) string {
+	if len(items) == 0 {
+		return "none"
+	}
+	return strings.Join(items, " ")
+}
+
+// svgCell is the size in pixels of a cell in the SVG heatmaps.
+const svgCell = 24
+
+// WriteSVG draws a heatmap of the cell occupancy of each piece, side by
+// side in rows of up to four, shading each cell from white where the piece
+// never goes to dark blue where it goes in every solution.
+func (an Analysis) WriteSVG(w io.Writer) error {
+
+	const perRow = 4
+	const label = 20
+	pw, ph := an.Cols*svgCell+svgCell, an.Rows*svgCell+label+svgCell/2
+	n := len(an.Pieces)
+	width := min(n, perRow)*pw + svgCell
+	height := (n+perRow-1)/perRow*ph + svgCell/2
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<svg xmlns="http://www.w3.org/2000/svg" `+
+		`width="%d" height="%d" font-family="sans-serif" font-size="14">`+
+		"\n", width, height)
+	for i, pa := range an.Pieces {
+		x0, y0 := svgCell+i%perRow*pw, svgCell/2+i/perRow*ph
+		fmt.Fprintf(&sb, `<text x="%d" y="%d">%s</text>`+"\n", x0,
+			y0+label-6, html.EscapeString(an.name(pa.ID)))
+		for r, row := range pa.Occupancy {
+			for c, count := range row {
+				f := an.percent(count) / 100
+				fill := fmt.Sprintf("rgb(%d,%d,%d)", int(255-247*f),
+					int(255-207*f), int(255-148*f))
+				fmt.Fprintf(&sb, `<rect x="%d" y="%d" width="%d" `+
+					`height="%d" fill="%s" stroke="#999">`+
+					`<title>%.0f%%</title></rect>`+"\n", x0+c*svgCell,
+					y0+label+r*svgCell, svgCell, svgCell, fill, 100*f)
+			}
+		}
+	}
+	sb.WriteString("</svg>\n")
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
@@ -0,0 +1,98 @@
+// -*- tab-width: 4; -*-
+
+package board
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/garyjg/shapepuzzle/mask"
+	"github.com/garyjg/shapepuzzle/shape"
+)
+
+func TestAnalysis(t *testing.T) {
+
+	// The bar fills either row of a 2x4 board, and the two dominoes fill
+	// the other row in either order, but those four solutions are all the
+	// same when the board is turned or flipped over.
+	shapes := shape.MakeShapes([][][]int{{{1, 1}}, {{1, 1}}, {{1, 1, 1, 1}}})
+	b := NewBoard(2, 4)
+	a := NewAnalyzer(b, shapes)
+	for sol := range solve(t, b, shapes, Options{Strategy: FirstCell}) {
+		a.Add(sol)
+	}
+	an := a.Analysis()
+	if an.Solutions != 1 || an.Symmetries != 4 {
+		t.Fatalf("Analyzed %d solutions up to %d symmetries, want 1 up "+
+			"to 4", an.Solutions, an.Symmetries)
+	}
+	got := fmt.Sprint(an.Pieces[0].Occupancy)
+	if want := "[[0 0 0 0] [0 0 1 1]]"; got != want {
+		t.Errorf("Domino occupancy is %s, want %s", got, want)
+	}
+	if got = fmt.Sprint(an.Pieces[2].Orientations); got != "[1 0]" {
+		t.Errorf("Bar orientations are %s", got)
+	}
+	if got = fmt.Sprint(an.Pairs); got != "[{1 2 1} {1 3 1} {2 3 1}]" {
+		t.Errorf("Adjacent pairs are %s", got)
+	}
+	if len(an.Fixed) != 3 || an.Fixed[0].Mask != mask.Bits(0xf)<<60 {
+		t.Errorf("Placements in every solution: %v", an.Fixed)
+	}
+
+	var sb strings.Builder
+	if err := an.WriteTable(&sb); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"[   0   0 100 100]",
+		"up to 4 symmetries", "always adjacent: 1-2 1-3 2-3",
+		"in every solution: 3 2 1"} {
+		if !strings.Contains(sb.String(), want) {
+			t.Errorf("Table is missing %q:\n%s", want, sb.String())
+		}
+	}
+	sb.Reset()
+	if err := an.WriteSVG(&sb); err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(sb.String(), "<rect "); n != 24 {
+		t.Errorf("Heatmap has %d cells, want 24", n)
+	}
+
+	// The eight solutions of the 5x5 puzzle are the turns and flips of
+	// one, so each piece goes in one orientation, not evenly in all.
+	b = NewBoard(5, 5)
+	shapes = puzzleShapes()
+	a = NewAnalyzer(b, shapes)
+	for sol := range solve(t, b, shapes, Options{Strategy: FirstCell}) {
+		a.Add(sol)
+	}
+	an = a.Analysis()
+	if an.Solutions != 1 || an.Symmetries != 8 {
+		t.Errorf("Analyzed %d solutions up to %d symmetries, want 1 up "+
+			"to 8", an.Solutions, an.Symmetries)
+	}
+	for _, pa := range an.Pieces {
+		used := 0
+		for _, n := range pa.Orientations {
+			if n > 0 {
+				used++
+			}
+		}
+		if used != 1 {
+			t.Errorf("Piece %d is used in %d orientations: %v", pa.ID,
+				used, pa.Orientations)
+		}
+	}
+
+	// A hole off the middle and the diagonals leaves the board with no
+	// symmetries but the identity.
+	holed := NewBoard(5, 5).Place(shape.FromMask(0, mask.Cell(0, 1)))
+	if n := len(holed.symmetries()); n != 0 {
+		t.Errorf("Board with a hole has %d symmetries", n)
+	}
+	if n := len(NewBoard(4, 4).WithTopology(Cylinder).symmetries()); n != 3 {
+		t.Errorf("Square cylinder has %d symmetries, want 3", n)
+	}
+}
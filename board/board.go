@@ -46,8 +46,10 @@
 package board
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"log/slog"
+	"time"
 
 	"github.com/garyjg/shapepuzzle/mask"
 	"github.com/garyjg/shapepuzzle/shape"
@@ -62,6 +64,7 @@ type Board struct {
 	ncols      int
 	mask       mask.Bits
 	placements []shape.Shape
+	topology   Topology
 }
 
 // NewBoard initializes a new Board with nrows rows and ncols columns.
@@ -120,26 +123,39 @@ func (b Board) Place(p shape.Shape) Board {
 	return nb
 }
 
-func (b Board) String() string {
+// LogValue formats the Board for log/slog only when a record is actually
+// written, since String is expensive.
+func (b Board) LogValue() slog.Value {
+	return slog.StringValue(b.String())
+}
 
-	// Fill the spots on the board with each shape's ID.
-	nrow := b.NumRows()
-	ncol := b.NumCols()
-	buf := ""
-	grid := make([][]int, nrow)
-	for r := 0; r < nrow; r++ {
-		grid[r] = make([]int, ncol)
-		buf += "["
-		for c := 0; c < ncol; c++ {
-			var mbits mask.Bits
-			mbits = mask.FirstBit().Translate(r, c)
+// Grid returns the ID of the shape placed on each cell of the board, a row
+// at a time, with 0 for the empty cells.
+func (b Board) Grid() [][]int {
+	grid := make([][]int, b.NumRows())
+	for r := range grid {
+		grid[r] = make([]int, b.NumCols())
+		for c := range grid[r] {
+			mbits := mask.Cell(r, c)
 			for _, p := range b.placements {
 				if p.Mask()&mbits != 0 {
 					grid[r][c] = p.ID()
 					break
 				}
 			}
-			buf += fmt.Sprintf(" %2d", grid[r][c])
+		}
+	}
+	return grid
+}
+
+func (b Board) String() string {
+
+	// Fill the spots on the board with each shape's ID.
+	buf := ""
+	for _, row := range b.Grid() {
+		buf += "["
+		for _, id := range row {
+			buf += fmt.Sprintf(" %2d", id)
 		}
 		buf += "]\n"
 	}
@@ -150,35 +166,49 @@ func (b Board) String() string {
 type Channel chan Board
 
 // FirstPlacements generates every permutation of the shape at every position in
-// the quadrant and push it to the channel, unless it matches one of the reject
-// patterns.
+// the quadrant and push it to the channel, unless it overlaps the cells already
+// filled or matches one of the reject patterns.  On a cylinder or torus the
+// positions are cut down further, since a solution can be slid round the board
+// as well.
 func FirstPlacements(s shape.Shape, b Board, bc Channel) {
+	firstPlacements(s, b, bc, GapShapes(b), newStageStats(0, s),
+		slog.Default())
+}
+
+func firstPlacements(s shape.Shape, b Board, bc Channel,
+	rejects []shape.Shape, st *StageStats, logger *slog.Logger) {
 
-	rejects := GapShapes(b)
-	perms := s.Permutations()
+	start := time.Now()
+	debug := logger.Enabled(context.Background(), slog.LevelDebug)
 	ngen, nrej := 0, 0
-	for _, p := range perms {
-		height := p.NumRows()
-		width := p.NumCols()
-		for r := 0; r <= b.NumRows()/2 && r <= b.NumRows()-height; r++ {
-			for c := 0; c <= b.NumCols()/2 && c <= b.NumCols()-width; c++ {
-				place := p.Translate(r, c)
-				nb := b.Place(place)
-				if !rejectBoard(nb, rejects) {
-					log.Printf("Generating first placement (S#%d):\n%v",
-						place.ID(), nb)
-					bc <- nb
-					ngen++
-				} else {
-					log.Printf("Rejected first placement (S#%d):\n%v",
-						place.ID(), nb)
-					nrej++
-				}
+	for _, place := range b.translations(s, true) {
+		st.Tried++
+		if place.Mask()&b.Mask() != 0 {
+			st.reject(RuleCollision)
+			continue
+		}
+		nb := b.Place(place)
+		if slot := searchGap(nb, rejects); slot < 0 {
+			if debug {
+				logger.Debug("generating first placement",
+					"stage", st.Stage, "shape", place.ID(), "board", nb)
+			}
+			st.emit(bc, nb)
+			ngen++
+		} else {
+			if debug {
+				logger.Debug("rejected first placement",
+					"stage", st.Stage, "shape", place.ID(),
+					"gap", rejects[slot].ID(), "board", nb)
 			}
+			st.rejectGap(rejects[slot])
+			nrej++
 		}
 	}
-	log.Printf("Total first placements (S#%d): %d generated, %d rejected.",
-		s.ID(), ngen, nrej)
+	logger.Info("first placements done", "stage", st.Stage, "shape", s.ID(),
+		"generated", ngen, "rejected", nrej)
+	st.Time = time.Since(start)
+	st.finish()
 	close(bc)
 }
 
@@ -188,44 +218,53 @@ func FirstPlacements(s shape.Shape, b Board, bc Channel) {
 // placed successfully is passed to the moves Channel.
 func NextPlacements(s shape.Shape, base Board, boards Channel,
 	moves Channel) {
+	t := NewPlacementTable(base, []shape.Shape{s})
+	nextPlacements(s, t, 0, boards, moves, newStageStats(0, s),
+		slog.Default())
+}
 
-	// Generate all possible board masks for placing this shape.
-	placements := make([]shape.Shape, 100)
-	rejects := GapShapes(base)
-	placements = placements[0:0]
-	perms := s.Permutations()
-	for i := 0; i < len(perms); i++ {
-		s := &(perms[i])
-		width := s.NumCols()
-		height := s.NumRows()
-		for r := 0; r <= base.NumRows()-height; r++ {
-			for c := 0; c <= base.NumCols()-width; c++ {
-				place := (*s).Translate(r, c)
-				// If this shape at this place on a blank board would be
-				// rejected, then reject it for any board.
-				nb := base.Place(place)
-				if !rejectBoard(nb, rejects) {
-					placements = append(placements, place)
-				} else {
-					log.Printf("Rejected prepared placement (S#%d):\n%v",
-						place.ID(), nb)
-				}
-			}
-		}
-	}
+// nextPlacements places shape i of the PlacementTable.  The placements
+// which would leave a gap even on the starting board were rejected when
+// the table was built, so they are only counted here.
+func nextPlacements(s shape.Shape, t *PlacementTable, i int, boards Channel,
+	moves Channel, st *StageStats, logger *slog.Logger) {
+
+	start := time.Now()
+	debug := logger.Enabled(context.Background(), slog.LevelDebug)
+	placements := t.prepared[i]
+	rejects := t.gaps
+	st.Rejected[RulePrepared] += int64(len(t.Shapes[i].Gapped))
+	logger.Info("placements prepared", "stage", st.Stage, "shape", s.ID(),
+		"placements", len(placements), "rejected", st.Rejected[RulePrepared])
+	st.Time += time.Since(start)
 	// For each input board, find all the placements which fit, but reject the
 	// ones known to not have room for future placements.
 	for b := range boards {
+		start = time.Now()
+		st.Received++
 		for _, place := range placements {
+			st.Tried++
 			if b.Mask()&place.Mask() == 0 {
 				nb := b.Place(place)
-				if !rejectBoard(nb, rejects) {
-					log.Printf("Generating placement (S#%d):\n%v", place.ID(), nb)
-					moves <- nb
+				if slot := searchGap(nb, rejects); slot < 0 {
+					if debug {
+						logger.Debug("generating placement", "stage", st.Stage,
+							"shape", place.ID(), "board", nb)
+					}
+					st.emit(moves, nb)
+				} else {
+					st.rejectGap(rejects[slot])
 				}
+			} else {
+				st.reject(RuleCollision)
 			}
 		}
+		st.Time += time.Since(start)
+		st.received()
 	}
+	logger.Info("placements done", "stage", st.Stage, "shape", s.ID(),
+		"received", st.Received, "emitted", st.Emitted)
+	st.finish()
 	close(moves)
 }
 
@@ -237,6 +276,12 @@ func NextPlacements(s shape.Shape, base Board, boards Channel,
 // a solution to the puzzle.
 //
 func (b Board) Solve(shapes []shape.Shape) Channel {
+	return b.solvePipeline(shapes, Options{})
+}
+
+// solvePipeline starts the goroutines for Solve, recording the work done by
+// each one in the Stats and reporting progress to the Observer in opts.
+func (b Board) solvePipeline(shapes []shape.Shape, opts Options) Channel {
 
 	nshapes := len(shapes)
 
@@ -245,13 +290,30 @@ func (b Board) Solve(shapes []shape.Shape) Channel {
 	for i := 0; i < nshapes; i++ {
 		channels[i] = make(Channel, 10000)
 	}
+	stages := make([]*StageStats, nshapes)
+	for i := 0; i < nshapes; i++ {
+		stages[i] = newStageStats(i, shapes[i])
+	}
+	if opts.Stats != nil {
+		opts.Stats.start(Pipeline, nshapes, stages)
+	}
+	if opts.Observer != nil {
+		pc := newProgressCounter(opts)
+		for _, st := range stages {
+			st.progress = pc
+		}
+	}
+	stages[nshapes-1].last = true
 
 	// Chain the channels.  Generate first placements for the first shape,
 	// and tell it to put those new boards on its channel.
-	go FirstPlacements(shapes[0], b, channels[0])
+	logger := opts.logger()
+	t := opts.table(b, shapes)
+	go firstPlacements(shapes[0], b, channels[0], t.gaps, stages[0], logger)
 
 	for i := 1; i < nshapes; i++ {
-		go NextPlacements(shapes[i], b, channels[i-1], channels[i])
+		go nextPlacements(shapes[i], t, i, channels[i-1], channels[i],
+			stages[i], logger)
 	}
 
 	// Finally listen for a solution (or not) to be pushed to the last
@@ -284,7 +346,9 @@ func searchGap(b Board, patterns []shape.Shape) int {
 	return -1
 }
 
-func rejectBoard(b Board, patterns []shape.Shape) bool {
+// RejectBoard returns true if the board has a gap which matches one of the
+// patterns from GapShapes, so that no shape could fill it.
+func RejectBoard(b Board, patterns []shape.Shape) bool {
 	return searchGap(b, patterns) >= 0
 }
 
@@ -310,6 +374,10 @@ func GapShapes(b Board) []shape.Shape {
 	for id, g := range grids {
 		s := shape.NewShape(id+100, g)
 		perms := s.Permutations()
+		if b.topology != Plane {
+			shapes = append(shapes, b.wrapGapShapes(perms)...)
+			continue
+		}
 		for i := 0; i < len(perms); i++ {
 			s := &(perms[i])
 			width := s.NumCols()
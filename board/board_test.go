@@ -3,6 +3,9 @@
 package board
 
 import (
+	"bytes"
+	"log/slog"
+	"strings"
 	"testing"
 
 	"github.com/garyjg/shapepuzzle/mask"
@@ -11,8 +14,14 @@ import (
 
 func TestRegionMask(t *testing.T) {
 
-	if NewBoard(4, 4).RegionMask() != mask.Bits(0xf0f0f0f000000000) {
-		t.Errorf("Wrong region mask for 5x5")
+	want, _ := mask.ParseGrid(`
+		####
+		####
+		####
+		####
+	`)
+	if got := NewBoard(4, 4).RegionMask(); got != want {
+		t.Errorf("Wrong region mask for 4x4:\n%s", got.Grid(8, 8))
 	}
 	if NewBoard(8, 8).RegionMask() != mask.Bits(0xffffffffffffffff) {
 		t.Errorf("Wrong region mask for 8x8")
@@ -30,10 +39,15 @@ func testShapes() []shape.Shape {
 func checkReject(t *testing.T, b Board, rejects []shape.Shape, expected bool) {
 	slot := searchGap(b, rejects)
 	reject := (slot >= 0)
+	grid := b.Mask().Grid(b.NumRows(), b.NumCols())
 	if !reject && expected {
-		t.Errorf("Board should be rejected: %v", b)
+		t.Errorf("Board should be rejected:\n%s", grid)
 	} else if reject && !expected {
-		t.Errorf("Board should NOT be rejected: %v\n%v", b, rejects[slot])
+		gap := rejects[slot]
+		t.Errorf("Board should NOT be rejected:\n%s\nby gap #%d outline:\n%s"+
+			"\nand gaps:\n%s", grid, gap.ID(),
+			gap.OutlineMask().Grid(b.NumRows(), b.NumCols()),
+			gap.GapMask().Grid(b.NumRows(), b.NumCols()))
 	}
 }
 
@@ -55,3 +69,181 @@ func TestRejectBoard(t *testing.T) {
 	checkReject(t, b.Place(shapes[2].Translate(3, 0)), rejects, true)
 
 }
+
+func puzzleShapes() []shape.Shape {
+	grids := [][][]int{{
+		{1, 1, 1}, {1, 0, 0}, {1, 0, 0}, {1, 0, 0}}, {
+		{1, 1, 0}, {1, 1, 1}}, {
+		{1, 1, 1}, {0, 1, 0}}, {
+		{0, 0, 1, 1}, {1, 1, 1, 0}}, {
+		{1, 0, 1}, {1, 1, 1}}}
+	return shape.MakeShapes(grids)
+}
+
+// solve starts SolveOptions, failing the test if it returns an error.
+func solve(t *testing.T, b Board, shapes []shape.Shape, opts Options) Channel {
+	t.Helper()
+	bc, err := b.SolveOptions(shapes, opts)
+	if err != nil {
+		t.Fatalf("SolveOptions failed: %v", err)
+	}
+	return bc
+}
+
+func countSolutions(t *testing.T, bc Channel, region mask.Bits) int {
+	n := 0
+	for b := range bc {
+		if b.Mask() != region {
+			t.Errorf("Solution does not fill the board:\n%v", b)
+		}
+		n++
+	}
+	return n
+}
+
+func TestCellStrategies(t *testing.T) {
+
+	b := NewBoard(5, 5)
+	shapes := puzzleShapes()
+
+	first := countSolutions(t,
+		solve(t, b, shapes, Options{Strategy: FirstCell}), b.RegionMask())
+	constrained := countSolutions(t,
+		solve(t, b, shapes, Options{Strategy: ConstrainedCell}), b.RegionMask())
+	if first == 0 {
+		t.Errorf("FirstCell search found no solutions")
+	}
+	if first != constrained {
+		t.Errorf("FirstCell found %d solutions but ConstrainedCell found %d",
+			first, constrained)
+	}
+}
+
+func TestPipelineHoles(t *testing.T) {
+
+	// Two I pentominoes fill the 3x5 board below its row of holes in two
+	// ways, and fill the 5x3 board right of its column of holes in two.
+	// The first holes are not the same flipped top to bottom, nor the
+	// second left to right, so the pipeline may not cut down where the
+	// first shape goes along those sides.
+	tests := []struct {
+		rows, cols int
+		holes      string
+		grids      [][][]int
+	}{
+		{3, 5, "#####", [][][]int{{{1, 1, 1, 1, 1}}, {{1, 1, 1, 1, 1}}}},
+		{5, 3, "#\n#\n#\n#\n#", [][][]int{
+			{{1}, {1}, {1}, {1}, {1}}, {{1}, {1}, {1}, {1}, {1}}}},
+	}
+	for _, test := range tests {
+		holes, err := mask.ParseGrid(test.holes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b := NewBoard(test.rows, test.cols).Place(shape.FromMask(0, holes))
+		shapes := shape.MakeShapes(test.grids)
+		cell := countSolutions(t,
+			solve(t, b, shapes, Options{Strategy: FirstCell}), b.RegionMask())
+		pipeline := countSolutions(t,
+			solve(t, b, shapes, Options{Strategy: Pipeline}), b.RegionMask())
+		if cell != 2 || pipeline != 2 {
+			t.Errorf("%dx%d board with holes:\n%s\nFirstCell found %d "+
+				"solutions and the pipeline %d, want 2", test.rows,
+				test.cols, holes.Grid(test.rows, test.cols), cell, pipeline)
+		}
+	}
+}
+
+func TestParallelSearch(t *testing.T) {
+
+	b := NewBoard(5, 5)
+	shapes := puzzleShapes()
+
+	serial := countSolutions(t,
+		solve(t, b, shapes, Options{Strategy: FirstCell}), b.RegionMask())
+	for _, workers := range []int{2, 4, 16} {
+		opts := Options{Strategy: FirstCell, Workers: workers}
+		got := countSolutions(t, solve(t, b, shapes, opts), b.RegionMask())
+		if got != serial {
+			t.Errorf("%d workers found %d solutions, serial search found %d",
+				workers, got, serial)
+		}
+	}
+}
+
+func TestMaxSolutions(t *testing.T) {
+
+	b := NewBoard(5, 5)
+	shapes := puzzleShapes()
+	for _, workers := range []int{1, 4} {
+		opts := Options{Strategy: ConstrainedCell, Workers: workers,
+			MaxSolutions: 3, Stats: &Stats{}, Checkpoint: &Checkpoint{}}
+		got := countSolutions(t, solve(t, b, shapes, opts), b.RegionMask())
+		if got != 3 || opts.Stats.Solutions != 3 ||
+			opts.Checkpoint.NumSolutions() != 3 {
+			t.Errorf("%d workers found %d solutions, %d in stats, %d in the "+
+				"checkpoint, want 3", workers, got, opts.Stats.Solutions,
+				opts.Checkpoint.NumSolutions())
+		}
+	}
+}
+
+func TestMaxShapes(t *testing.T) {
+
+	b := NewBoard(8, 8)
+	grids := [][][]int{}
+	for len(grids) < MaxShapes {
+		grids = append(grids, [][]int{{1}})
+	}
+	shapes := shape.MakeShapes(grids)
+	opts := Options{Strategy: FirstCell, MaxSolutions: 1}
+	if n := countSolutions(t, solve(t, b, shapes, opts),
+		b.RegionMask()); n != 1 {
+		t.Errorf("%d monominoes found %d solutions, want 1", MaxShapes, n)
+	}
+
+	shapes = append(shapes, shapes[0])
+	for _, strategy := range []Strategy{Pipeline, FirstCell} {
+		opts.Strategy = strategy
+		if _, err := b.SolveOptions(shapes, opts); err == nil {
+			t.Errorf("%v search should reject %d shapes", strategy,
+				len(shapes))
+		}
+	}
+}
+
+func TestParseStrategy(t *testing.T) {
+	for _, s := range []Strategy{Pipeline, FirstCell, ConstrainedCell} {
+		got, err := ParseStrategy(s.String())
+		if err != nil || got != s {
+			t.Errorf("ParseStrategy(%q) = %v, %v", s.String(), got, err)
+		}
+	}
+	if _, err := ParseStrategy("bogus"); err == nil {
+		t.Errorf("ParseStrategy should reject unknown names")
+	}
+}
+
+func TestLogger(t *testing.T) {
+
+	b := NewBoard(5, 5)
+	shapes := puzzleShapes()
+
+	for _, strategy := range []Strategy{Pipeline, FirstCell} {
+		for _, level := range []slog.Level{slog.LevelInfo, slog.LevelDebug} {
+			var buf bytes.Buffer
+			h := slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: level})
+			opts := Options{Strategy: strategy, Logger: slog.New(h)}
+			countSolutions(t, solve(t, b, shapes, opts), b.RegionMask())
+			out := buf.String()
+			if !strings.Contains(out, "level=INFO") {
+				t.Errorf("%v search logged no info records", strategy)
+			}
+			debug := strings.Contains(out, "level=DEBUG")
+			if debug != (level == slog.LevelDebug) {
+				t.Errorf("%v search at level %v: debug records %v",
+					strategy, level, debug)
+			}
+		}
+	}
+}
@@ -0,0 +1,212 @@
+// -*- tab-width: 4; -*-
+
+package board
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/garyjg/shapepuzzle/mask"
+	"github.com/garyjg/shapepuzzle/shape"
+)
+
+// Placement identifies one shape placed on a board by its shape ID and its
+// mask on the board.
+type Placement struct {
+	ID   int       `json:"id"`
+	Mask mask.Bits `json:"mask"`
+}
+
+// Checkpoint records the progress of a cell search so that it can be
+// resumed after the process stops.  Done holds the tasks at the top of the
+// search tree which have been searched completely, each one identified by
+// its path of (shape index, placement index) pairs.  Solutions holds every
+// solution found so far, including those found in tasks which were not
+// finished.
+//
+// A Checkpoint passed in Options is updated while the search runs, and
+// WriteFile may be called at any time to save a consistent snapshot.
+type Checkpoint struct {
+	Puzzle    string        `json:"puzzle"`
+	Done      [][]int       `json:"done"`
+	Solutions [][]Placement `json:"solutions"`
+
+	mu        sync.Mutex
+	done      map[string]bool
+	solutions map[string]bool
+}
+
+// ReadCheckpoint loads a Checkpoint written by WriteFile.
+func ReadCheckpoint(path string) (*Checkpoint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cp := &Checkpoint{}
+	if err := json.Unmarshal(data, cp); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return cp, nil
+}
+
+// WriteFile saves the Checkpoint to path atomically: it is written to a
+// temporary file in the same directory which then replaces path, so an
+// interrupted write never leaves a truncated checkpoint behind.
+func (cp *Checkpoint) WriteFile(path string) error {
+
+	cp.mu.Lock()
+	data, err := json.Marshal(cp)
+	cp.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+// writeFileAtomic writes data to a temporary file in the same directory as
+// path, which then replaces path, so that a reader sees either the old
+// file or the new one and never a partial write.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// NumSolutions returns the number of solutions recorded in the Checkpoint.
+func (cp *Checkpoint) NumSolutions() int {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	return len(cp.Solutions)
+}
+
+// Verify returns an error if the Checkpoint holds progress from a search
+// for a different puzzle than the given board and shapes, or with a
+// different strategy or shard in opts.  A new, empty Checkpoint matches
+// any search.
+func (cp *Checkpoint) Verify(b Board, shapes []shape.Shape,
+	opts Options) error {
+
+	fp := fingerprint(b, shapes, opts)
+	if cp.Puzzle != "" && cp.Puzzle != fp {
+		return fmt.Errorf("checkpoint is for puzzle %s, not %s", cp.Puzzle, fp)
+	}
+	return nil
+}
+
+// fingerprint identifies a search by hashing everything which determines
+// the order of the search tree, so task paths are only reused for the
+// same search.
+func fingerprint(b Board, shapes []shape.Shape, opts Options) string {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%dx%d %v %v %d %v", b.NumRows(), b.NumCols(), b.Mask(),
+		opts.Strategy, splitDepth, opts.Shard)
+	if b.Topology() != Plane {
+		fmt.Fprintf(h, " %v", b.Topology())
+	}
+	if opts.Optional {
+		fmt.Fprintf(h, " optional")
+	}
+	for _, s := range shapes {
+		fmt.Fprintf(h, " %d:%v", s.ID(), s.Mask())
+	}
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+// start prepares the Checkpoint for a search, indexing the progress from
+// an earlier run if there is any.
+func (cp *Checkpoint) start(fp string) {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	cp.Puzzle = fp
+	cp.done = make(map[string]bool)
+	for _, path := range cp.Done {
+		cp.done[fmt.Sprint(path)] = true
+	}
+	cp.solutions = make(map[string]bool)
+	for _, sol := range cp.Solutions {
+		cp.solutions[solutionKey(sol)] = true
+	}
+}
+
+// isDone reports whether the task with the given path was finished.
+func (cp *Checkpoint) isDone(path []int) bool {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	return cp.done[fmt.Sprint(path)]
+}
+
+// finish records the task with the given path as searched completely.
+func (cp *Checkpoint) finish(path []int) {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	cp.Done = append(cp.Done, path)
+	cp.done[fmt.Sprint(path)] = true
+}
+
+// recorded reports whether the solution was recorded already, by an
+// earlier run of the search.
+func (cp *Checkpoint) recorded(sol []Placement) bool {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	return cp.solutions[solutionKey(sol)]
+}
+
+// add records a solution.
+func (cp *Checkpoint) add(sol []Placement) {
+	key := solutionKey(sol)
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	cp.solutions[key] = true
+	cp.Solutions = append(cp.Solutions, sol)
+}
+
+// solutionKey is the same for any two lists of the same placements,
+// whatever order the placements were made in.
+func solutionKey(sol []Placement) string {
+	keys := make([]string, len(sol))
+	for i, p := range sol {
+		keys[i] = fmt.Sprintf("%d:%v", p.ID, p.Mask)
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, " ")
+}
+
+// taskPath converts the placements at the top of the search tree into the
+// path which identifies the task in a Checkpoint.
+func taskPath(stack []cellPlacement) []int {
+	path := make([]int, 0, 2*len(stack))
+	for _, cp := range stack {
+		path = append(path, cp.shape, cp.place)
+	}
+	return path
+}
+
+// placements converts a stack of placements into shape IDs and masks.
+func (ci *cellIndex) placements(stack []cellPlacement) []Placement {
+	sol := make([]Placement, len(stack))
+	for i, cp := range stack {
+		sol[i] = Placement{ci.places[cp.shape][cp.place].ID(), cp.mask}
+	}
+	return sol
+}
@@ -0,0 +1,118 @@
+// -*- tab-width: 4; -*-
+
+package board
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/garyjg/shapepuzzle/shape"
+)
+
+func TestCheckpointResume(t *testing.T) {
+
+	b := NewBoard(5, 5)
+	shapes := puzzleShapes()
+
+	full := &Checkpoint{}
+	opts := Options{Strategy: FirstCell, Workers: 2, Checkpoint: full}
+	total := countSolutions(t, solve(t, b, shapes, opts), b.RegionMask())
+	if full.NumSolutions() != total {
+		t.Fatalf("checkpoint holds %d solutions, search found %d",
+			full.NumSolutions(), total)
+	}
+
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+	if err := full.WriteFile(path); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	cp, err := ReadCheckpoint(path)
+	if err != nil {
+		t.Fatalf("ReadCheckpoint failed: %v", err)
+	}
+	if err := cp.Verify(b, shapes, opts); err != nil {
+		t.Errorf("Verify failed: %v", err)
+	}
+	if err := cp.Verify(b, shapes[1:], opts); err == nil {
+		t.Errorf("Verify should reject a checkpoint for other shapes")
+	}
+
+	// Resuming a finished search finds nothing more.
+	opts.Checkpoint = cp
+	if n := countSolutions(t, solve(t, b, shapes, opts),
+		b.RegionMask()); n != 0 {
+		t.Errorf("resuming a finished search found %d solutions", n)
+	}
+
+	// Resuming with only some of the solutions and none of the tasks
+	// recorded finds exactly the rest.
+	partial := &Checkpoint{Puzzle: cp.Puzzle, Solutions: cp.Solutions[:total/2]}
+	seen := map[string]bool{}
+	for _, sol := range partial.Solutions {
+		seen[solutionKey(sol)] = true
+	}
+	opts.Checkpoint = partial
+	n := 0
+	for sb := range solve(t, b, shapes, opts) {
+		var sol []Placement
+		for _, p := range sb.placements {
+			sol = append(sol, Placement{p.ID(), p.Mask()})
+		}
+		if seen[solutionKey(sol)] {
+			t.Errorf("resumed search repeated a solution:\n%v", sb)
+		}
+		n++
+	}
+	if n != total-total/2 {
+		t.Errorf("resumed search found %d solutions, expected %d",
+			n, total-total/2)
+	}
+	if partial.NumSolutions() != total {
+		t.Errorf("resumed checkpoint holds %d solutions, expected %d",
+			partial.NumSolutions(), total)
+	}
+}
+
+func TestCheckpointDelivered(t *testing.T) {
+
+	// The search waits for each solution to be taken from the channel
+	// before recording it, so the checkpoint never runs ahead.
+	b := NewBoard(5, 5)
+	cp := &Checkpoint{}
+	opts := Options{Strategy: FirstCell, Workers: 2, Checkpoint: cp}
+	bc := solve(t, b, puzzleShapes(), opts)
+	<-bc
+	time.Sleep(20 * time.Millisecond)
+	if n := cp.NumSolutions(); n > 1 {
+		t.Errorf("checkpoint holds %d solutions after one was received", n)
+	}
+	for range bc {
+	}
+}
+
+func TestCheckpointStopped(t *testing.T) {
+
+	// A search stopped by MaxSolutions leaves the task it was in
+	// unfinished, so resuming it finds every solution it did not report.
+	// Four dominoes fill a 2x4 board in 120 ways, with several below each
+	// task.
+	b := NewBoard(2, 4)
+	shapes := shape.MakeShapes([][][]int{
+		{{1, 1}}, {{1, 1}}, {{1, 1}}, {{1, 1}}})
+	total := countSolutions(t,
+		solve(t, b, shapes, Options{Strategy: FirstCell}), b.RegionMask())
+	for _, workers := range []int{1, 4} {
+		cp := &Checkpoint{}
+		opts := Options{Strategy: FirstCell, Workers: workers,
+			MaxSolutions: 1, Checkpoint: cp}
+		first := countSolutions(t, solve(t, b, shapes, opts), b.RegionMask())
+		opts.MaxSolutions = 0
+		rest := countSolutions(t, solve(t, b, shapes, opts), b.RegionMask())
+		if first+rest != total || cp.NumSolutions() != total {
+			t.Errorf("%d workers found %d solutions and %d more on resuming, "+
+				"%d in the checkpoint, want %d", workers, first, rest,
+				cp.NumSolutions(), total)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+// -*- tab-width: 4; -*-
+
+package board
+
+import (
+	"fmt"
+	"log/slog"
+	"math/bits"
+	"sort"
+	"time"
+
+	"github.com/garyjg/shapepuzzle/mask"
+	"github.com/garyjg/shapepuzzle/shape"
+)
+
+// PackOptions controls a Pack search.
+type PackOptions struct {
+	// Keep is the number of packings covering the most cells to return.
+	// Zero means one.
+	Keep int
+
+	// MaxNodes stops the search after that many nodes of the search tree,
+	// so that the best packings found by then are returned without a proof
+	// that nothing covers more.  Zero means no limit.
+	MaxNodes int64
+
+	// Placements and Logger are used as they are in Options.
+	Placements *PlacementTable
+	Logger     *slog.Logger
+}
+
+// Packing is the result of a Pack search.
+type Packing struct {
+	// Covered is the number of cells covered by each of the Boards.
+	Covered int
+
+	// Boards are the packings found which cover Covered cells, in the
+	// order they were found.
+	Boards []Board
+
+	// Bound is the most cells any packing could cover: the smaller of the
+	// empty cells on the board and the total area of the shapes.
+	Bound int
+
+	// Optimal is true when the search proved that no packing covers more
+	// than Covered cells, by searching the whole tree or by reaching Bound.
+	Optimal bool
+
+	// Nodes is the number of nodes of the search tree visited.
+	Nodes int64
+}
+
+// packer is the state of a Pack search.
+type packer struct {
+	ci       *cellIndex
+	keep     int
+	maxNodes int64
+	bound    int
+	nodes    int64
+	best     int
+	stacks   [][]cellPlacement
+	stopped  bool
+}
+
+// Pack searches for the placements of any subset of the shapes, each used
+// at most once, which cover as many of the empty cells on the board as
+// possible.  It is a branch and bound search: the first empty cell is
+// either covered by a placement of an unused shape or left empty, and a
+// branch is cut off when the cells covered so far plus an upper bound on
+// the cells the unused shapes could still cover is no better than the best
+// packing found.  The bound gives each separate region of undecided cells
+// the largest sum of unused shape areas which fits in it, and is never
+// more than the total area of the unused shapes.
+func (b Board) Pack(shapes []shape.Shape, opts PackOptions) Packing {
+
+	logger := opts.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	t := Options{Placements: opts.Placements, Logger: logger}.table(b, shapes)
+	pk := &packer{ci: t.index, keep: max(opts.Keep, 1),
+		maxNodes: opts.MaxNodes}
+	area := 0
+	for _, s := range shapes {
+		area += s.Mask().Count()
+	}
+	pk.bound = min(area, (pk.ci.region &^ b.Mask()).Count())
+
+	start := time.Now()
+	logger.Info("packing started", "shapes", len(shapes),
+		"placements", pk.ci.size(), "bound", pk.bound)
+	pk.step(b.Mask(), 0, 0, nil, true)
+	p := Packing{Covered: pk.best, Bound: pk.bound,
+		Optimal: !pk.stopped || pk.best == pk.bound, Nodes: pk.nodes}
+	for _, stack := range pk.stacks {
+		p.Boards = append(p.Boards, pk.ci.board(b, stack))
+	}
+	logger.Info("packing done", "covered", p.Covered, "bound", p.Bound,
+		"optimal", p.Optimal, "packings", len(p.Boards), "nodes", p.Nodes,
+		"elapsed", time.Since(start))
+	return p
+}
+
+// done returns true once the search can stop: when it has run out of
+// nodes, or has kept enough packings which cover every cell they could.
+func (pk *packer) done() bool {
+	return pk.stopped || (pk.best == pk.bound && len(pk.stacks) >= pk.keep)
+}
+
+// step searches below the node whose decided cells are filled, with the
+// shapes in used placed to cover covered cells by the placements on the
+// stack.  A node reached by a placement is a packing in itself, so it is
+// kept if it is one of the best so far.
+func (pk *packer) step(filled mask.Bits, used uint64, covered int,
+	stack []cellPlacement, placed bool) {
+
+	if pk.nodes++; pk.maxNodes > 0 && pk.nodes > pk.maxNodes {
+		pk.stopped = true
+		return
+	}
+	if placed {
+		if covered > pk.best {
+			pk.best = covered
+			pk.stacks = pk.stacks[:0]
+		}
+		if covered == pk.best && len(pk.stacks) < pk.keep {
+			pk.stacks = append(pk.stacks, append([]cellPlacement{}, stack...))
+		}
+	}
+	limit := covered + pk.ci.packBound(filled, used)
+	if limit < pk.best || (limit == pk.best && len(pk.stacks) >= pk.keep) {
+		return
+	}
+	cell := pk.ci.firstCell(filled)
+	if cell < 0 {
+		return
+	}
+	for _, cp := range pk.ci.cells[cell] {
+		if used&(1<<uint(cp.shape)) != 0 || cp.mask&filled != 0 {
+			continue
+		}
+		pk.step(filled|cp.mask, used|1<<uint(cp.shape),
+			covered+pk.ci.areas[cp.shape], append(stack, cp), true)
+		if pk.done() {
+			return
+		}
+	}
+	pk.step(filled|mask.FirstBit()>>uint(cell), used, covered, stack, false)
+}
+
+// packBound returns the most cells the unused shapes could cover in the
+// empty regions of the filled mask.  Each region can hold no more than the
+// largest sum of unused areas which fits in it, as in fillable.
+func (ci *cellIndex) packBound(filled mask.Bits, used uint64) int {
+
+	sums, total := uint64(1), 0
+	for i, area := range ci.areas {
+		if used&(1<<uint(i)) == 0 {
+			total += area
+			if area < 64 {
+				sums |= sums << uint(area)
+			}
+		}
+	}
+	bound := 0
+	empty := ci.region &^ filled
+	for rest := empty; rest != 0 && bound < total; {
+		region := ci.flood(rest&-rest, empty)
+		rest &^= region
+		n := region.Count()
+		if n >= 64 {
+			bound += n
+			continue
+		}
+		bound += bits.Len64(sums&(1<<uint(n+1)-1)) - 1
+	}
+	return min(bound, total)
+}
+
+// SmallestBoard returns the packing of every shape on the smallest board
+// they fit on without overlapping, up to maxRows by maxCols.  Boards are
+// tried in order of area and then of rows, from the total area of the
+// shapes up.  It returns an error if the shapes fit on none of them, or
+// if a search was stopped by opts.MaxNodes before it could tell.
+func SmallestBoard(shapes []shape.Shape, maxRows int, maxCols int,
+	opts PackOptions) (Packing, error) {
+
+	if maxRows < 1 || maxRows > 8 || maxCols < 1 || maxCols > 8 {
+		return Packing{}, fmt.Errorf("board is %dx%d, but must be from "+
+			"1x1 to 8x8", maxRows, maxCols)
+	}
+	area := 0
+	for _, s := range shapes {
+		area += s.Mask().Count()
+	}
+	type size struct{ rows, cols int }
+	sizes := []size{}
+	for r := 1; r <= maxRows; r++ {
+		for c := 1; c <= maxCols; c++ {
+			if r*c >= area {
+				sizes = append(sizes, size{r, c})
+			}
+		}
+	}
+	sort.SliceStable(sizes, func(i, j int) bool {
+		return sizes[i].rows*sizes[i].cols < sizes[j].rows*sizes[j].cols
+	})
+	opts.Keep, opts.Placements = 1, nil
+	for _, sz := range sizes {
+		p := NewBoard(sz.rows, sz.cols).Pack(shapes, opts)
+		if p.Covered == area {
+			return p, nil
+		}
+		if !p.Optimal {
+			return p, fmt.Errorf("search of the %dx%d board stopped after "+
+				"%d nodes", sz.rows, sz.cols, p.Nodes)
+		}
+	}
+	return Packing{}, fmt.Errorf("shapes with %d cells fit on no board up "+
+		"to %dx%d", area, maxRows, maxCols)
+}
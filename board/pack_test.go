@@ -0,0 +1,72 @@
+// -*- tab-width: 4; -*-
+
+package board
+
+import (
+	"testing"
+
+	"github.com/garyjg/shapepuzzle/shape"
+)
+
+func TestPack(t *testing.T) {
+
+	// An L tromino and a domino do not both fit in a 2x2 square, so the
+	// best packing leaves one cell empty.
+	shapes := shape.MakeShapes([][][]int{{{1, 1}, {1, 0}}, {{1, 1}}})
+	p := NewBoard(2, 2).Pack(shapes, PackOptions{Keep: 100})
+	if p.Covered != 3 || p.Bound != 4 || !p.Optimal {
+		t.Errorf("Packing covers %d of %d cells, optimal %v", p.Covered,
+			p.Bound, p.Optimal)
+	}
+	// The tromino goes in any of its four orientations.
+	if len(p.Boards) != 4 {
+		t.Errorf("Found %d best packings, want 4", len(p.Boards))
+	}
+	for _, b := range p.Boards {
+		if b.Mask().Count() != 3 || b.NumShapes() != 1 {
+			t.Errorf("Packing is not the tromino alone:\n%v", b)
+		}
+	}
+
+	// An exact fill reaches the bound, which ends the search.
+	b := NewBoard(5, 5)
+	p = b.Pack(puzzleShapes(), PackOptions{})
+	if p.Covered != 25 || !p.Optimal || len(p.Boards) != 1 ||
+		p.Boards[0].Mask() != b.RegionMask() {
+		t.Errorf("Packing covers %d cells, optimal %v", p.Covered, p.Optimal)
+	}
+
+	// Once it has reached the bound, a search which runs out of nodes
+	// looking for more packings has still proved its coverage.
+	full := b.Pack(puzzleShapes(), PackOptions{Keep: 100})
+	p = b.Pack(puzzleShapes(),
+		PackOptions{Keep: 100, MaxNodes: full.Nodes - 1})
+	if p.Covered != 25 || !p.Optimal {
+		t.Errorf("Packing stopped at the bound covers %d cells, optimal %v",
+			p.Covered, p.Optimal)
+	}
+
+	// A search stopped early proves nothing.
+	p = NewBoard(2, 2).Pack(shapes, PackOptions{MaxNodes: 2})
+	if p.Optimal {
+		t.Errorf("Search stopped after %d nodes is optimal", p.Nodes)
+	}
+}
+
+func TestSmallestBoard(t *testing.T) {
+
+	dominoes := shape.MakeShapes([][][]int{{{1, 1}}, {{1, 1}}, {{1, 1}}})
+	p, err := SmallestBoard(dominoes, 8, 8, PackOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := p.Boards[0]
+	if b.NumRows() != 1 || b.NumCols() != 6 || p.Covered != 6 {
+		t.Errorf("Smallest board is %dx%d:\n%v", b.NumRows(), b.NumCols(), b)
+	}
+
+	// The pieces of the 5x5 puzzle do not fit in four rows of six.
+	if _, err := SmallestBoard(puzzleShapes(), 4, 6, PackOptions{}); err == nil {
+		t.Errorf("Puzzle pieces fit on a 4x6 board")
+	}
+}
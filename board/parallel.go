@@ -0,0 +1,240 @@
+// -*- tab-width: 4; -*-
+
+package board
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/garyjg/shapepuzzle/mask"
+)
+
+// splitDepth is the number of placements at the top of the search tree
+// which are expanded into separate tasks.  Below that depth each task is
+// searched depth first on the worker which took it.
+const splitDepth = 2
+
+// task is a node in the top levels of the search tree: the placements
+// made so far and the resulting board mask.  First is the index of the
+// first-level placement the task descends from, or -1 for the root.
+type task struct {
+	filled mask.Bits
+	used   uint64
+	stack  []cellPlacement
+	first  int
+}
+
+// deque holds the tasks queued by one worker.  The worker pushes and pops
+// tasks at the back, and idle workers steal from the front, where the
+// tasks are closest to the root and so are likely to be the largest.
+type deque struct {
+	mu    sync.Mutex
+	tasks []task
+}
+
+func (d *deque) push(t task) {
+	d.mu.Lock()
+	d.tasks = append(d.tasks, t)
+	d.mu.Unlock()
+}
+
+func (d *deque) pop() (task, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	n := len(d.tasks)
+	if n == 0 {
+		return task{}, false
+	}
+	t := d.tasks[n-1]
+	d.tasks = d.tasks[:n-1]
+	return t, true
+}
+
+func (d *deque) steal() (task, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.tasks) == 0 {
+		return task{}, false
+	}
+	t := d.tasks[0]
+	d.tasks = d.tasks[1:]
+	return t, true
+}
+
+// workPool is a set of workers, each with its own deque of tasks and its
+// own searcher.  The pending count is the number of tasks which have been
+// pushed but not yet finished, so the search is complete when it drops to
+// zero.  Likewise subtrees counts the unfinished tasks below each
+// first-level placement, so the progress can count finished placements.
+type workPool struct {
+	ci         *cellIndex
+	logger     *slog.Logger
+	debug      bool
+	checkpoint *Checkpoint
+	shard      Shard
+	progress   *progressCounter
+	deques     []*deque
+	searchers  []*searcher
+	subtrees   []int64
+	pending    int64
+}
+
+// parallel runs the cell search on the number of workers in opts, but at
+// least one.  Every worker searches its tasks depth first on its own stack,
+// so only the solutions passed to found ever leave a worker.  It returns
+// the counters for the work done by each worker.
+func (ci *cellIndex) parallel(filled mask.Bits, opts Options,
+	progress *progressCounter,
+	found func(stack []cellPlacement)) [][]depthCounts {
+
+	workers := opts.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	wp := &workPool{ci: ci, checkpoint: opts.Checkpoint, shard: opts.Shard,
+		progress: progress, logger: opts.logger()}
+	wp.debug = wp.logger.Enabled(context.Background(), slog.LevelDebug)
+	wp.deques = make([]*deque, workers)
+	wp.searchers = make([]*searcher, workers)
+	for i := range wp.deques {
+		wp.deques[i] = &deque{}
+		wp.searchers[i] = ci.newSearcher(opts, progress, found)
+	}
+	wp.pending = 1
+	wp.deques[0].push(task{filled: filled, first: -1})
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func(id int) {
+			wp.work(id)
+			wp.searchers[id].flush()
+			wg.Done()
+		}(i)
+	}
+	wg.Wait()
+	counts := make([][]depthCounts, workers)
+	for i, sr := range wp.searchers {
+		counts[i] = sr.counts
+	}
+	return counts
+}
+
+// work runs tasks from the worker's own deque, or steals them from the
+// other workers when its own deque is empty, until no tasks are pending.
+func (wp *workPool) work(id int) {
+	own := wp.deques[id]
+	idle := time.Duration(0)
+	for {
+		t, ok := own.pop()
+		for i := 1; !ok && i < len(wp.deques); i++ {
+			t, ok = wp.deques[(id+i)%len(wp.deques)].steal()
+		}
+		if !ok {
+			if atomic.LoadInt64(&wp.pending) == 0 {
+				return
+			}
+			// Back off while the remaining tasks are running on other
+			// workers, which may still split them into new tasks.
+			if idle < time.Millisecond {
+				idle += 10 * time.Microsecond
+			}
+			time.Sleep(idle)
+			continue
+		}
+		idle = 0
+		wp.done(t, wp.run(own, t, wp.searchers[id]))
+	}
+}
+
+// done counts a task which is no longer pending.  If it was finished it
+// also counts the first-level placement it descends from once every task
+// below that placement is finished, but a task stopped early is left
+// unfinished.
+func (wp *workPool) done(t task, finished bool) {
+	if finished && t.first >= 0 &&
+		atomic.AddInt64(&wp.subtrees[t.first], -1) == 0 {
+		atomic.AddInt64(&wp.progress.firstDone, 1)
+	}
+	atomic.AddInt64(&wp.pending, -1)
+}
+
+// run expands a task near the root into one new task for each placement
+// which fits on the next cell, or searches it depth first once it is
+// deep enough in the tree.  It returns false if the search was stopped
+// before the task was finished, once it found as many solutions as it
+// was asked for, so that the task is not recorded in the checkpoint.
+func (wp *workPool) run(own *deque, t task, sr *searcher) bool {
+	ci := wp.ci
+	if wp.progress.enough() {
+		return false
+	}
+	if len(t.stack) >= splitDepth {
+		path := taskPath(t.stack)
+		if !wp.shard.contains(path) {
+			return true
+		}
+		if wp.checkpoint != nil && wp.checkpoint.isDone(path) {
+			return true
+		}
+		if wp.debug {
+			wp.logger.Debug("searching task", "path", path,
+				"filled", t.filled)
+		}
+		stack := make([]cellPlacement, len(t.stack), ci.nshape)
+		copy(stack, t.stack)
+		sr.search(t.filled, t.used, stack)
+		if wp.progress.enough() {
+			return false
+		}
+		if wp.checkpoint != nil {
+			wp.checkpoint.finish(path)
+		}
+		return true
+	}
+	dc := &sr.counts[len(t.stack)]
+	dc.received++
+	sr.nodes++
+	cell := ci.nextCell(t.filled, t.used, sr.strategy)
+	if cell < 0 {
+		if ci.complete(t.used, sr.optional) &&
+			wp.shard.contains(taskPath(t.stack)) {
+			sr.found(t.stack)
+		}
+		return true
+	}
+	children := []task{}
+	for _, cp := range ci.cells[cell] {
+		if !ci.try(cp, t.filled, t.used, dc) {
+			continue
+		}
+		stack := make([]cellPlacement, len(t.stack), len(t.stack)+1)
+		copy(stack, t.stack)
+		children = append(children, task{
+			filled: t.filled | cp.mask,
+			used:   t.used | 1<<uint(cp.shape),
+			stack:  append(stack, cp),
+			first:  t.first,
+		})
+	}
+	if t.first < 0 {
+		// The children of the root are the first-level placements, and
+		// each one starts as the only unfinished task in its subtree.
+		wp.subtrees = make([]int64, len(children))
+		for i := range children {
+			children[i].first = i
+			wp.subtrees[i] = 1
+		}
+		atomic.StoreInt64(&wp.progress.firstTotal, int64(len(children)))
+	} else {
+		atomic.AddInt64(&wp.subtrees[t.first], int64(len(children)))
+	}
+	atomic.AddInt64(&wp.pending, int64(len(children)))
+	for _, child := range children {
+		own.push(child)
+	}
+	return true
+}
@@ -0,0 +1,266 @@
+// -*- tab-width: 4; -*-
+
+package board
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"os"
+
+	"github.com/garyjg/shapepuzzle/mask"
+	"github.com/garyjg/shapepuzzle/shape"
+)
+
+// PlacementTable holds every placement of each shape in a set which fits
+// on one board, so the placements can be generated once and shared by
+// every stage of a search and by any number of searches of the same
+// puzzle.  The placements of each shape are every permutation translated
+// to every position where it does not overlap the cells already filled
+// on the board, wrapping around the edges of a cylinder or torus.  They
+// are indexed by the cells they cover as well.
+//
+// A PlacementTable can be saved with WriteFile and read back with
+// ReadPlacementTable, as a cache for boards with many placements.  It is
+// safe to use from any number of goroutines once it is built.
+type PlacementTable struct {
+	Puzzle   string            `json:"puzzle"`
+	Rows     int               `json:"rows"`
+	Cols     int               `json:"cols"`
+	Topology Topology          `json:"topology,omitempty"`
+	Filled   mask.Bits         `json:"filled"`
+	Shapes   []ShapePlacements `json:"shapes"`
+	Sum      string            `json:"sum"`
+
+	index    *cellIndex
+	gaps     []shape.Shape
+	prepared [][]shape.Shape
+}
+
+// ShapePlacements lists the masks of the placements of one shape, with
+// the indexes of the masks which leave a gap matching one of the GapShapes
+// templates even on the starting board.  Those placements can never be
+// part of a solution, but the gap templates are only correct for shapes
+// of five or more cells, so only the Pipeline strategy leaves them out.
+type ShapePlacements struct {
+	ID     int         `json:"id"`
+	Area   int         `json:"area"`
+	Masks  []mask.Bits `json:"masks"`
+	Gapped []int       `json:"gapped,omitempty"`
+}
+
+// NewPlacementTable translates every permutation of each shape to every
+// position on the board.
+func NewPlacementTable(b Board, shapes []shape.Shape) *PlacementTable {
+
+	t := &PlacementTable{Puzzle: tableKey(b, shapes), Rows: b.NumRows(),
+		Cols: b.NumCols(), Topology: b.Topology(), Filled: b.Mask()}
+	gaps := GapShapes(b)
+	for _, s := range shapes {
+		sp := ShapePlacements{ID: s.ID(), Area: s.Mask().Count()}
+		for _, place := range b.translations(s, false) {
+			if place.Mask()&b.Mask() != 0 {
+				continue
+			}
+			if RejectBoard(b.Place(place), gaps) {
+				sp.Gapped = append(sp.Gapped, len(sp.Masks))
+			}
+			sp.Masks = append(sp.Masks, place.Mask())
+		}
+		t.Shapes = append(t.Shapes, sp)
+	}
+	t.Sum = t.placementSum()
+	t.build(gaps)
+	return t
+}
+
+// ReadPlacementTable loads a PlacementTable written by WriteFile.
+func ReadPlacementTable(path string) (*PlacementTable, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	t := &PlacementTable{}
+	if err := json.Unmarshal(data, t); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if err := t.check(); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	b := NewBoard(t.Rows, t.Cols).WithTopology(t.Topology)
+	t.build(GapShapes(b))
+	return t, nil
+}
+
+// check returns an error if the PlacementTable read from a file could not
+// have been used for any search: if its board is too big, it has more than
+// MaxShapes shapes, a placement is empty, falls off the board, covers a
+// filled cell or is not the area of its shape, an index of a gapped
+// placement is out of range, or the placements do not match their
+// checksum.
+func (t *PlacementTable) check() error {
+	if t.Rows < 1 || t.Rows > 8 || t.Cols < 1 || t.Cols > 8 {
+		return fmt.Errorf("board is %dx%d", t.Rows, t.Cols)
+	}
+	if len(t.Shapes) > MaxShapes {
+		return fmt.Errorf("table has %d shapes", len(t.Shapes))
+	}
+	region := NewBoard(t.Rows, t.Cols).RegionMask()
+	if t.Filled&^region != 0 {
+		return fmt.Errorf("filled cells %v are off the board", t.Filled)
+	}
+	for _, sp := range t.Shapes {
+		for _, m := range sp.Masks {
+			if m == 0 || m&^region != 0 || m&t.Filled != 0 ||
+				m.Count() != sp.Area {
+				return fmt.Errorf("shape %d has a bad placement %v",
+					sp.ID, m)
+			}
+		}
+		for _, j := range sp.Gapped {
+			if j < 0 || j >= len(sp.Masks) {
+				return fmt.Errorf("shape %d has a bad gapped index %d",
+					sp.ID, j)
+			}
+		}
+	}
+	if sum := t.placementSum(); t.Sum != sum {
+		return fmt.Errorf("placement checksum is %s, not %s", sum, t.Sum)
+	}
+	return nil
+}
+
+// WriteFile saves the PlacementTable to path as JSON, atomically as
+// Checkpoint.WriteFile does, so an interrupted write never leaves a
+// truncated table behind.
+func (t *PlacementTable) WriteFile(path string) error {
+	data, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+// Verify returns an error if the PlacementTable was built for a different
+// board or set of shapes.  Besides the key stored in the table, it checks
+// the board size, topology and filled cells and the ID and area of each
+// shape, and that the placements still match the checksum taken when they
+// were listed, so a table edited or damaged since is not trusted.  None of
+// this translates a shape, so verifying a cached table costs much less
+// than building it again.
+func (t *PlacementTable) Verify(b Board, shapes []shape.Shape) error {
+	if key := tableKey(b, shapes); t.Puzzle != key {
+		return fmt.Errorf("placement table is for puzzle %s, not %s",
+			t.Puzzle, key)
+	}
+	if t.Rows != b.NumRows() || t.Cols != b.NumCols() ||
+		t.Topology != b.Topology() || t.Filled != b.Mask() {
+		return fmt.Errorf("placement table is for a %dx%d %v board with "+
+			"%v filled", t.Rows, t.Cols, t.Topology, t.Filled)
+	}
+	if len(t.Shapes) != len(shapes) {
+		return fmt.Errorf("placement table has %d shapes, not %d",
+			len(t.Shapes), len(shapes))
+	}
+	for i, s := range shapes {
+		sp := t.Shapes[i]
+		if sp.ID != s.ID() || sp.Area != s.Mask().Count() {
+			return fmt.Errorf("placement table shape %d is %d of area %d, "+
+				"not %d of area %d", i, sp.ID, sp.Area, s.ID(),
+				s.Mask().Count())
+		}
+	}
+	if sum := t.placementSum(); t.Sum != sum {
+		return fmt.Errorf("placement table checksum is %s, not %s", sum,
+			t.Sum)
+	}
+	return nil
+}
+
+// Len returns the number of placements in the table.
+func (t *PlacementTable) Len() int {
+	return t.index.size()
+}
+
+// Covering returns every placement in the table which covers the cell at
+// row r and column c, ordered by shape, or none if the cell is off the
+// board.
+func (t *PlacementTable) Covering(r int, c int) []Placement {
+	covering := []Placement{}
+	if r < 0 || r >= t.Rows || c < 0 || c >= t.Cols {
+		return covering
+	}
+	for _, cp := range t.index.cells[r*8+c] {
+		covering = append(covering, Placement{t.Shapes[cp.shape].ID, cp.mask})
+	}
+	return covering
+}
+
+// table returns the PlacementTable in opts if it was built for the board
+// and shapes, or else a new one.
+func (opts Options) table(b Board, shapes []shape.Shape) *PlacementTable {
+	t := opts.Placements
+	if t == nil {
+		return NewPlacementTable(b, shapes)
+	}
+	if err := t.Verify(b, shapes); err != nil {
+		opts.logger().Warn("rebuilding placements", "err", err)
+		return NewPlacementTable(b, shapes)
+	}
+	return t
+}
+
+// build makes the Shapes for the placements and indexes them by cell.
+func (t *PlacementTable) build(gaps []shape.Shape) {
+
+	b := NewBoard(t.Rows, t.Cols).WithTopology(t.Topology)
+	ci := &cellIndex{region: b.RegionMask(), nshape: len(t.Shapes)}
+	if b.Topology() != Plane {
+		ci.neighbors = b.neighbors
+	}
+	ci.places = make([][]shape.Shape, len(t.Shapes))
+	ci.areas = make([]int, len(t.Shapes))
+	t.prepared = make([][]shape.Shape, len(t.Shapes))
+	for i, sp := range t.Shapes {
+		ci.areas[i] = sp.Area
+		gapped := map[int]bool{}
+		for _, j := range sp.Gapped {
+			gapped[j] = true
+		}
+		for j, m := range sp.Masks {
+			place := shape.FromMask(sp.ID, m)
+			cp := cellPlacement{i, j, m}
+			ci.places[i] = append(ci.places[i], place)
+			for r, c := range m.Cells() {
+				ci.cells[r*8+c] = append(ci.cells[r*8+c], cp)
+			}
+			if !gapped[j] {
+				t.prepared[i] = append(t.prepared[i], place)
+			}
+		}
+	}
+	t.index = ci
+	t.gaps = gaps
+}
+
+// placementSum returns a checksum of the placements listed in the table.
+func (t *PlacementTable) placementSum() string {
+	h := fnv.New64a()
+	for _, sp := range t.Shapes {
+		fmt.Fprintf(h, "%d:%d %v %v;", sp.ID, sp.Area, sp.Masks, sp.Gapped)
+	}
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+// tableKey identifies the board and shapes a PlacementTable is built for.
+func tableKey(b Board, shapes []shape.Shape) string {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%dx%d %v", b.NumRows(), b.NumCols(), b.Mask())
+	if b.Topology() != Plane {
+		fmt.Fprintf(h, " %v", b.Topology())
+	}
+	for _, s := range shapes {
+		fmt.Fprintf(h, " %d:%v", s.ID(), s.Mask())
+	}
+	return fmt.Sprintf("%016x", h.Sum64())
+}
@@ -0,0 +1,99 @@
+// -*- tab-width: 4; -*-
+
+package board
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/garyjg/shapepuzzle/mask"
+)
+
+func TestPlacementTable(t *testing.T) {
+
+	b := NewBoard(5, 5)
+	shapes := puzzleShapes()
+	table := NewPlacementTable(b, shapes)
+
+	n := 0
+	for _, sp := range table.Shapes {
+		n += len(sp.Masks)
+	}
+	if table.Len() != n || n == 0 {
+		t.Errorf("Len() = %d with %d masks", table.Len(), n)
+	}
+	for _, p := range table.Covering(2, 3) {
+		if p.Mask&mask.Cell(2, 3) == 0 {
+			t.Errorf("placement %d:%v does not cover (2, 3)", p.ID, p.Mask)
+		}
+	}
+	if n := len(table.Covering(5, 0)) + len(table.Covering(0, -1)); n != 0 {
+		t.Errorf("%d placements cover cells off the board", n)
+	}
+
+	path := filepath.Join(t.TempDir(), "placements.json")
+	if err := table.WriteFile(path); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	cached, err := ReadPlacementTable(path)
+	if err != nil {
+		t.Fatalf("ReadPlacementTable failed: %v", err)
+	}
+	if err := cached.Verify(b, shapes); err != nil {
+		t.Errorf("Verify failed: %v", err)
+	}
+	if err := cached.Verify(b, shapes[1:]); err == nil {
+		t.Errorf("Verify should reject a table for other shapes")
+	}
+	if files, _ := os.ReadDir(filepath.Dir(path)); len(files) != 1 {
+		t.Errorf("WriteFile left %d files behind", len(files))
+	}
+
+	// A table whose placements were changed after it was written is not
+	// trusted, even though its key still matches.
+	damaged, _ := ReadPlacementTable(path)
+	masks := damaged.Shapes[0].Masks
+	masks[0], masks[1] = masks[1], masks[0]
+	if err := damaged.Verify(b, shapes); err == nil {
+		t.Errorf("Verify should reject a table with the wrong placements")
+	}
+	damaged.Shapes[0].Masks = append(masks, masks[0])
+	if err := damaged.Verify(b, shapes); err == nil {
+		t.Errorf("Verify should reject a table with extra placements")
+	}
+	damaged.Shapes[0].Masks[0] = mask.Cell(4, 4) | mask.Cell(4, 5)
+	bad := filepath.Join(t.TempDir(), "bad.json")
+	if err := damaged.WriteFile(bad); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadPlacementTable(bad); err == nil {
+		t.Errorf("ReadPlacementTable should reject a placement off the " +
+			"board")
+	}
+	damaged.Shapes[0].Masks[0] = masks[1]
+	if err := damaged.WriteFile(bad); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadPlacementTable(bad); err == nil {
+		t.Errorf("ReadPlacementTable should reject placements which do " +
+			"not match their checksum")
+	}
+
+	// The same table serves every strategy and search, and a table for
+	// another puzzle is ignored.
+	want := countSolutions(t, solve(t, b, shapes, Options{}),
+		b.RegionMask())
+	other := NewPlacementTable(NewBoard(4, 4), shapes)
+	for _, tt := range []*PlacementTable{table, cached, other} {
+		for _, strategy := range []Strategy{Pipeline, FirstCell} {
+			opts := Options{Strategy: strategy, Placements: tt}
+			got := countSolutions(t, solve(t, b, shapes, opts),
+				b.RegionMask())
+			if got != want {
+				t.Errorf("%v search with table %s found %d solutions, "+
+					"want %d", strategy, tt.Puzzle, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,149 @@
+// -*- tab-width: 4; -*-
+
+package board
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// progressBatch is the number of nodes a searcher counts on its own before
+// adding them to the shared progress counter.
+const progressBatch = 4096
+
+// Progress is a snapshot of a running search, as passed to the Observer in
+// Options.  Nodes is the number of boards searched so far, which are the
+// boards passed between the stages for the Pipeline strategy.  FirstDone
+// of the FirstTotal placements at the first level of the search tree have
+// been searched completely.  Done is true for the last snapshot, once the
+// search is finished.
+//
+// For the Pipeline strategy FirstTotal is the number of boards produced by
+// FirstPlacements so far, and a first placement counts as done once the
+// second stage has placed the next shape on it.  The rest of the pipeline
+// may still be working on those boards, so the estimate runs ahead.
+type Progress struct {
+	Nodes      int64
+	Solutions  int64
+	FirstDone  int64
+	FirstTotal int64
+	Elapsed    time.Duration
+	Done       bool
+}
+
+// Fraction estimates the fraction of the search tree which has been
+// searched from the first-level placements which are done.
+func (p Progress) Fraction() float64 {
+	if p.Done {
+		return 1
+	}
+	if p.FirstTotal == 0 {
+		return 0
+	}
+	return float64(p.FirstDone) / float64(p.FirstTotal)
+}
+
+// NodesPerSecond returns the average search rate so far.
+func (p Progress) NodesPerSecond() float64 {
+	if p.Elapsed <= 0 {
+		return 0
+	}
+	return float64(p.Nodes) / p.Elapsed.Seconds()
+}
+
+// progressCounter holds the counters shared by every goroutine of a
+// search, and calls the observer with snapshots of them until stopped.
+type progressCounter struct {
+	nodes      int64
+	solutions  int64
+	firstDone  int64
+	firstTotal int64
+
+	// limit is the most solutions the search reports, or zero for no
+	// limit, and full is set once it has reported that many.
+	limit int64
+	full  int32
+
+	began    time.Time
+	observer func(Progress)
+	quit     chan bool
+	stopped  chan bool
+}
+
+// newProgressCounter starts the counters for a search, along with a
+// goroutine calling the observer in opts, if there is one.
+func newProgressCounter(opts Options) *progressCounter {
+	pc := &progressCounter{began: time.Now(), observer: opts.Observer,
+		limit: opts.MaxSolutions}
+	if pc.observer == nil {
+		return pc
+	}
+	interval := opts.ObserveInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	pc.quit = make(chan bool)
+	pc.stopped = make(chan bool)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				pc.observer(pc.snapshot(false))
+			case <-pc.quit:
+				pc.observer(pc.snapshot(true))
+				close(pc.stopped)
+				return
+			}
+		}
+	}()
+	return pc
+}
+
+func (pc *progressCounter) snapshot(done bool) Progress {
+	return Progress{
+		Nodes:      atomic.LoadInt64(&pc.nodes),
+		Solutions:  atomic.LoadInt64(&pc.solutions),
+		FirstDone:  atomic.LoadInt64(&pc.firstDone),
+		FirstTotal: atomic.LoadInt64(&pc.firstTotal),
+		Elapsed:    time.Since(pc.began),
+		Done:       done,
+	}
+}
+
+// stop reports the final Progress to the observer and waits for it to
+// return, so the observer is never called after the search is done.
+func (pc *progressCounter) stop() {
+	if pc.quit != nil {
+		close(pc.quit)
+		<-pc.stopped
+	}
+}
+
+// claim counts a solution and returns true if the search may report it,
+// which is always unless the search has a limit and has reached it.
+func (pc *progressCounter) claim() bool {
+	if pc.limit <= 0 {
+		atomic.AddInt64(&pc.solutions, 1)
+		return true
+	}
+	for {
+		n := atomic.LoadInt64(&pc.solutions)
+		if n >= pc.limit {
+			return false
+		}
+		if atomic.CompareAndSwapInt64(&pc.solutions, n, n+1) {
+			if n+1 == pc.limit {
+				atomic.StoreInt32(&pc.full, 1)
+			}
+			return true
+		}
+	}
+}
+
+// enough returns true once the search has reported as many solutions as
+// it was asked for.
+func (pc *progressCounter) enough() bool {
+	return atomic.LoadInt32(&pc.full) != 0
+}
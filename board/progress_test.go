@@ -0,0 +1,61 @@
+// -*- tab-width: 4; -*-
+
+package board
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestProgress(t *testing.T) {
+
+	b := NewBoard(5, 5)
+	shapes := puzzleShapes()
+
+	for _, opts := range []Options{
+		{Strategy: Pipeline},
+		{Strategy: FirstCell},
+		{Strategy: FirstCell, Workers: 4},
+	} {
+		var mu sync.Mutex
+		reports := []Progress{}
+		opts.Observer = func(p Progress) {
+			mu.Lock()
+			reports = append(reports, p)
+			mu.Unlock()
+		}
+		opts.ObserveInterval = time.Millisecond
+		opts.Stats = &Stats{}
+		n := countSolutions(t, solve(t, b, shapes, opts), b.RegionMask())
+
+		mu.Lock()
+		if len(reports) == 0 {
+			t.Fatalf("%v: observer was never called", opts.Strategy)
+		}
+		last := reports[len(reports)-1]
+		mu.Unlock()
+		if !last.Done || last.Fraction() != 1 {
+			t.Errorf("%v: last progress is not done: %+v", opts.Strategy, last)
+		}
+		if last.Solutions != int64(n) {
+			t.Errorf("%v: progress counted %d solutions, search found %d",
+				opts.Strategy, last.Solutions, n)
+		}
+		if opts.Strategy == Pipeline {
+			continue
+		}
+		if last.FirstTotal == 0 || last.FirstDone != last.FirstTotal {
+			t.Errorf("%v: %d of %d first placements done", opts.Strategy,
+				last.FirstDone, last.FirstTotal)
+		}
+		var nodes int64
+		for _, st := range opts.Stats.Stages {
+			nodes += st.Received
+		}
+		if last.Nodes != nodes {
+			t.Errorf("%v: progress counted %d nodes, stats counted %d",
+				opts.Strategy, last.Nodes, nodes)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+// -*- tab-width: 4; -*-
+
+package board
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"math/bits"
+	"text/tabwriter"
+
+	"github.com/garyjg/shapepuzzle/mask"
+	"github.com/garyjg/shapepuzzle/shape"
+)
+
+// Rating is a measure of how hard a puzzle is, with the numbers it was
+// worked out from.
+type Rating struct {
+	// Score grows with the size of the search tree for each solution, in
+	// bits, and shrinks by up to half as more of the steps to the first
+	// solution are forced: log2(1 + Nodes/Solutions) * (1 - ForcedSteps/
+	// Steps/2), with at least one solution counted.
+	Score float64 `json:"score"`
+
+	// Solutions and Nodes count the solutions and the nodes of the search
+	// tree of a complete ConstrainedCell search.
+	Solutions int64 `json:"solutions"`
+	Nodes     int64 `json:"nodes"`
+
+	// Placements is the number of placements which fit on the empty
+	// board, and Cells the number of cells to fill.
+	Placements int `json:"placements"`
+	Cells      int `json:"cells"`
+
+	// ForcedCells is the number of cells on the empty board which only one
+	// placement covers, so a human solver can place it straight away.
+	ForcedCells int `json:"forced_cells"`
+
+	// Steps is the number of placements in the first solution found, and
+	// ForcedSteps the number of those made on a board with a forced cell.
+	Steps       int `json:"steps"`
+	ForcedSteps int `json:"forced_steps"`
+
+	// Branching is the mean number of placements searched below each node
+	// at each depth of the search tree.
+	Branching []float64 `json:"branching"`
+
+	// Stats are the statistics of the search the rating was made from.
+	Stats *Stats `json:"-"`
+}
+
+// Rate rates the difficulty of filling the board with the shapes.  It
+// counts every solution with a ConstrainedCell search, which always fills
+// the cell with the fewest choices as a human solver would, using the
+// Workers, Placements and Logger in opts, and looks for forced cells in
+// the placement table.  The search is never stopped early, so any
+// MaxSolutions, Checkpoint, Shard or Optional in opts is ignored.  It
+// returns an error if SolveOptions cannot search the shapes.
+func (b Board) Rate(shapes []shape.Shape, opts Options) (Rating, error) {
+
+	t := opts.table(b, shapes)
+	ci := t.index
+	opts.Strategy = ConstrainedCell
+	opts.Placements = t
+	opts.Stats = &Stats{}
+	opts.Checkpoint, opts.Shard, opts.Optional = nil, Shard{}, false
+	opts.MaxSolutions = 0
+
+	r := Rating{Placements: t.Len(), Cells: (ci.region &^ b.Mask()).Count(),
+		Stats: opts.Stats}
+	solutions, err := b.SolveOptions(shapes, opts)
+	if err != nil {
+		return r, err
+	}
+	var first *Board
+	for sol := range solutions {
+		if first == nil {
+			first = &sol
+		}
+	}
+	r.ForcedCells = ci.forcedCells(b.Mask(), 0)
+
+	// Replay the first solution in the order its placements were made.
+	if first != nil {
+		index := map[int]int{}
+		for i, s := range shapes {
+			index[s.ID()] = i
+		}
+		filled, used := b.Mask(), uint64(0)
+		for _, p := range first.placements[len(b.placements):] {
+			r.Steps++
+			if ci.forcedCells(filled, used) > 0 {
+				r.ForcedSteps++
+			}
+			filled |= p.Mask()
+			used |= 1 << uint(index[p.ID()])
+		}
+	}
+
+	r.Solutions = opts.Stats.Solutions
+	for i, st := range opts.Stats.Stages {
+		r.Nodes += st.Received
+		if i < len(shapes) && st.Received > 0 {
+			r.Branching = append(r.Branching,
+				float64(st.Emitted)/float64(st.Received))
+		}
+	}
+	r.Score = math.Log2(1 + float64(r.Nodes)/float64(max(r.Solutions, 1)))
+	if r.Steps > 0 {
+		r.Score *= 1 - float64(r.ForcedSteps)/float64(r.Steps)/2
+	}
+	return r, nil
+}
+
+// forcedCells returns the number of empty cells which only one placement
+// of the unused shapes covers on the filled mask.
+func (ci *cellIndex) forcedCells(filled mask.Bits, used uint64) int {
+	n := 0
+	for m := ci.region &^ filled; m != 0; m &= m - 1 {
+		cell := 63 - bits.TrailingZeros64(uint64(m))
+		fits := 0
+		for _, cp := range ci.cells[cell] {
+			if used&(1<<uint(cp.shape)) == 0 && cp.mask&filled == 0 {
+				if fits++; fits > 1 {
+					break
+				}
+			}
+		}
+		if fits == 1 {
+			n++
+		}
+	}
+	return n
+}
+
+// WriteTable prints the Rating and its breakdown as a text table, with the
+// branching factor at each depth of the search tree.
+func (r Rating) WriteTable(w io.Writer) error {
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "difficulty\t%.1f\n", r.Score)
+	fmt.Fprintf(tw, "solutions\t%d\n", r.Solutions)
+	fmt.Fprintf(tw, "search nodes\t%d\n", r.Nodes)
+	fmt.Fprintf(tw, "placements\t%d\n", r.Placements)
+	fmt.Fprintf(tw, "forced cells\t%d of %d\n", r.ForcedCells, r.Cells)
+	fmt.Fprintf(tw, "forced steps\t%d of %d\n", r.ForcedSteps, r.Steps)
+	fmt.Fprintf(tw, "depth\tbranching\n")
+	for depth, bf := range r.Branching {
+		fmt.Fprintf(tw, "%d\t%.2f\n", depth, bf)
+	}
+	return tw.Flush()
+}
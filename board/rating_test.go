@@ -0,0 +1,48 @@
+// -*- tab-width: 4; -*-
+
+package board
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/garyjg/shapepuzzle/shape"
+)
+
+func TestRate(t *testing.T) {
+
+	b := NewBoard(5, 5)
+	r, err := b.Rate(puzzleShapes(), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Solutions != 8 || r.Nodes == 0 || r.Cells != 25 ||
+		r.Steps != 5 || len(r.Branching) != 5 {
+		t.Errorf("Rating %+v", r)
+	}
+	// A limit on the solutions would rate a search cut short.
+	limited, err := b.Rate(puzzleShapes(), Options{MaxSolutions: 1})
+	if err != nil || limited.Solutions != r.Solutions ||
+		limited.Nodes != r.Nodes {
+		t.Errorf("Rating with MaxSolutions %+v, %v", limited, err)
+	}
+	var buf bytes.Buffer
+	if err := r.WriteTable(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "forced steps") {
+		t.Errorf("WriteTable has no forced steps:\n%s", buf.String())
+	}
+
+	// Every cell of a row of two dominoes is forced once one is placed,
+	// which makes it easier than the 5x5 puzzle.
+	dominoes := shape.MakeShapes([][][]int{{{1, 1}}, {{1, 1}}})
+	easy, err := NewBoard(1, 4).Rate(dominoes, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if easy.ForcedSteps != 1 || easy.Score >= r.Score {
+		t.Errorf("Dominoes rated %+v, 5x5 puzzle %.1f", easy, r.Score)
+	}
+}
@@ -0,0 +1,413 @@
+// -*- tab-width: 4; -*-
+
+package board
+
+import (
+	"fmt"
+	"log/slog"
+	"math/bits"
+	"sync/atomic"
+	"time"
+
+	"github.com/garyjg/shapepuzzle/mask"
+	"github.com/garyjg/shapepuzzle/shape"
+)
+
+// Strategy selects the algorithm SolveOptions uses to search for
+// solutions.
+type Strategy int
+
+const (
+	// Pipeline chains one goroutine per shape, exactly like Solve.
+	Pipeline Strategy = iota
+	// FirstCell always fills the first empty cell on the board, trying
+	// only the placements of the remaining shapes which cover it.
+	FirstCell
+	// ConstrainedCell fills the empty cell which can be covered by the
+	// fewest placements of the remaining shapes.
+	ConstrainedCell
+)
+
+var strategyNames = []string{"pipeline", "firstcell", "constrained"}
+
+func (s Strategy) String() string {
+	if s < 0 || int(s) >= len(strategyNames) {
+		return fmt.Sprintf("Strategy(%d)", int(s))
+	}
+	return strategyNames[s]
+}
+
+// ParseStrategy returns the Strategy with the given name, as returned by
+// the Strategy String method.
+func ParseStrategy(name string) (Strategy, error) {
+	for i, n := range strategyNames {
+		if n == name {
+			return Strategy(i), nil
+		}
+	}
+	return Pipeline, fmt.Errorf("unknown search strategy: %s", name)
+}
+
+// Options controls how SolveOptions searches for solutions.  The zero
+// value is the same search as Solve.
+type Options struct {
+	Strategy Strategy
+
+	// Workers is the number of goroutines which share the cell search.
+	// Zero or one runs the whole search on one goroutine.  The Pipeline
+	// strategy always uses one goroutine per shape.
+	Workers int
+
+	// Observer, when not nil, is called with the Progress of the search
+	// every ObserveInterval, or every second if that is zero, and once more
+	// when the search is done.  It is called from its own goroutine.
+	Observer        func(Progress)
+	ObserveInterval time.Duration
+
+	// Checkpoint, when not nil, records the progress of a cell search as
+	// it runs.  If it already holds progress from an earlier run of the
+	// same search, as read by ReadCheckpoint, then the finished tasks are
+	// skipped and only the solutions which it does not hold yet are
+	// reported.  With a Checkpoint the Channel is unbuffered, and each
+	// solution is only recorded once the receiver has taken it, so that a
+	// checkpoint saved at any time holds no solution which was not
+	// delivered.  A task is recorded as finished after its solutions.
+	Checkpoint *Checkpoint
+
+	// Shard limits a cell search to one slice of the search tree.
+	Shard Shard
+
+	// Optional lets a cell search leave shapes off the board, so that
+	// every subset of the shapes which fills it exactly is a solution.
+	// The Pipeline strategy always places every shape.
+	Optional bool
+
+	// MaxSolutions stops a cell search once it has found that many
+	// solutions, and closes the Channel.  Zero means no limit.  The
+	// Pipeline strategy always finds every solution.
+	MaxSolutions int64
+
+	// Stats, when not nil, is filled in with the work done by each stage
+	// of the search by the time the solution Channel is closed.
+	Stats *Stats
+
+	// Placements, when not nil, is the PlacementTable for the board and
+	// shapes, so that it is not built again for every search.  A table for
+	// a different puzzle is ignored.
+	Placements *PlacementTable
+
+	// Logger receives the search's log messages: a summary of each stage
+	// at the info level, and a trace of every placement at the debug
+	// level.  Nil means slog.Default().
+	Logger *slog.Logger
+}
+
+func (opts Options) logger() *slog.Logger {
+	if opts.Logger == nil {
+		return slog.Default()
+	}
+	return opts.Logger
+}
+
+// MaxShapes is the most shapes SolveOptions can place, since a cell
+// search keeps the set of shapes it has placed in the bits of a uint64.
+const MaxShapes = 64
+
+// SolveOptions searches for solutions using the algorithm selected in
+// opts and returns the Channel on which solutions are reported.  The
+// channel is closed when the search completes.  It returns an error
+// without searching if there are more than MaxShapes shapes.
+func (b Board) SolveOptions(shapes []shape.Shape,
+	opts Options) (Channel, error) {
+
+	if len(shapes) > MaxShapes {
+		return nil, fmt.Errorf("%d shapes given, but a search can place "+
+			"at most %d", len(shapes), MaxShapes)
+	}
+	if opts.Strategy == Pipeline {
+		return b.solvePipeline(shapes, opts), nil
+	}
+	ci := opts.table(b, shapes).index
+	solutions := make(Channel, 100)
+	if opts.Checkpoint != nil {
+		solutions = make(Channel)
+	}
+	go func() {
+		pc := newProgressCounter(opts)
+		cp := opts.Checkpoint
+		found := func(stack []cellPlacement) {
+			var sol []Placement
+			if cp != nil {
+				if sol = ci.placements(stack); cp.recorded(sol) {
+					return
+				}
+			}
+			if !pc.claim() {
+				return
+			}
+			solutions <- ci.board(b, stack)
+			if cp != nil {
+				cp.add(sol)
+			}
+		}
+		if cp != nil {
+			cp.start(fingerprint(b, shapes, opts))
+		}
+		logger := opts.logger()
+		logger.Info("cell search started", "strategy", opts.Strategy,
+			"shapes", len(shapes), "placements", ci.size(),
+			"workers", opts.Workers, "shard", opts.Shard)
+		counts := ci.parallel(b.Mask(), opts, pc, found)
+		pc.stop()
+		logger.Info("cell search done", "strategy", opts.Strategy,
+			"nodes", pc.nodes, "solutions", pc.solutions,
+			"elapsed", time.Since(pc.began))
+		if opts.Stats != nil {
+			opts.Stats.start(opts.Strategy, len(counts), cellStats(counts))
+			opts.Stats.Solutions = pc.solutions
+			opts.Stats.Elapsed = time.Since(pc.began)
+		}
+		close(solutions)
+	}()
+	return solutions, nil
+}
+
+// cellPlacement is one translated permutation of a shape on the board.
+// The shape field is the index of the shape in the set being placed, and
+// place indexes that shape's placements in the cellIndex.
+type cellPlacement struct {
+	shape int
+	place int
+	mask  mask.Bits
+}
+
+// cellIndex holds the placements from a PlacementTable in the form the
+// cell search uses, indexed by the cells each placement covers.  Cell i is
+// the i'th bit counting from the most significant bit of the mask, so cell
+// 0 is the upper left corner, just like mask.FirstBit().  No gap templates
+// are applied, so the index is correct for shapes of any size.  On a
+// cylinder or torus, neighbors joins the cells across the edges which
+// wrap for the region pruning.
+type cellIndex struct {
+	region    mask.Bits
+	nshape    int
+	areas     []int
+	places    [][]shape.Shape
+	cells     [64][]cellPlacement
+	neighbors func(mask.Bits) mask.Bits
+}
+
+// size returns the number of placements in the index.
+func (ci *cellIndex) size() int {
+	n := 0
+	for _, places := range ci.places {
+		n += len(places)
+	}
+	return n
+}
+
+// firstCell returns the first cell in the region which is not filled, or
+// -1 if the region is full.
+func (ci *cellIndex) firstCell(filled mask.Bits) int {
+	empty := ci.region &^ filled
+	if empty == 0 {
+		return -1
+	}
+	return bits.LeadingZeros64(uint64(empty))
+}
+
+// constrainedCell returns the empty cell with the fewest placements of the
+// unused shapes which fit on the filled mask, or -1 if the region is full.
+func (ci *cellIndex) constrainedCell(filled mask.Bits, used uint64) int {
+	best, nbest := -1, 0
+	for m := ci.region &^ filled; m != 0; m &= m - 1 {
+		cell := 63 - bits.TrailingZeros64(uint64(m))
+		n := 0
+		for _, cp := range ci.cells[cell] {
+			if used&(1<<uint(cp.shape)) == 0 && cp.mask&filled == 0 {
+				n++
+			}
+		}
+		if best < 0 || n < nbest {
+			best, nbest = cell, n
+			if n <= 1 {
+				break
+			}
+		}
+	}
+	return best
+}
+
+// floodFill returns the cells in empty which are 4-connected to the cells
+// in seed.  Shifting by one bit moves a cell to the next column, so bits
+// which wrap around into the first or last column of another row are
+// masked out.
+func floodFill(seed mask.Bits, empty mask.Bits) mask.Bits {
+	const firstCol = mask.Bits(0x8080808080808080)
+	const lastCol = mask.Bits(0x0101010101010101)
+	for {
+		next := seed | (seed>>1)&^firstCol | (seed<<1)&^lastCol |
+			seed>>8 | seed<<8
+		next &= empty
+		if next == seed {
+			return seed
+		}
+		seed = next
+	}
+}
+
+// flood returns the cells in empty which are connected to the cells in
+// seed, across the edges which wrap if there are any.
+func (ci *cellIndex) flood(seed mask.Bits, empty mask.Bits) mask.Bits {
+	if ci.neighbors == nil {
+		return floodFill(seed, empty)
+	}
+	for {
+		next := (seed | ci.neighbors(seed)) & empty
+		if next == seed {
+			return seed
+		}
+		seed = next
+	}
+}
+
+// fillable reports whether every separate empty region on the board has
+// an area which some subset of the unused shapes could cover.  This is
+// the cell search's replacement for the gap templates, and it is only an
+// area check, so a true result does not mean the regions can be filled.
+func (ci *cellIndex) fillable(filled mask.Bits, used uint64) bool {
+
+	// Bit n of sums is set when n cells can be covered by some subset of
+	// the unused shapes.
+	sums := uint64(1)
+	for i, area := range ci.areas {
+		if used&(1<<uint(i)) == 0 && area < 64 {
+			sums |= sums << uint(area)
+		}
+	}
+	empty := ci.region &^ filled
+	for rest := empty; rest != 0; {
+		region := ci.flood(rest&-rest, empty)
+		if region == empty {
+			break
+		}
+		rest &^= region
+		if sums&(1<<uint(bits.OnesCount64(uint64(region)))) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// nextCell returns the empty cell the strategy fills next, or -1 if the
+// region is full.
+func (ci *cellIndex) nextCell(filled mask.Bits, used uint64,
+	strategy Strategy) int {
+
+	if strategy == ConstrainedCell {
+		return ci.constrainedCell(filled, used)
+	}
+	return ci.firstCell(filled)
+}
+
+// complete reports whether a full board with the shapes in used placed is
+// a solution: always if the shapes are optional, or else only once every
+// shape is placed.
+func (ci *cellIndex) complete(used uint64, optional bool) bool {
+	return optional || used == uint64(1)<<uint(ci.nshape)-1
+}
+
+// searcher holds the state of one goroutine searching the cellIndex: the
+// counters for the work done at each depth of the search tree, and the
+// number of nodes searched since they were last added to the progress.
+type searcher struct {
+	ci       *cellIndex
+	strategy Strategy
+	optional bool
+	counts   []depthCounts
+	found    func(stack []cellPlacement)
+	progress *progressCounter
+	nodes    int64
+}
+
+func (ci *cellIndex) newSearcher(opts Options, progress *progressCounter,
+	found func(stack []cellPlacement)) *searcher {
+
+	return &searcher{ci: ci, strategy: opts.Strategy, optional: opts.Optional,
+		counts: make([]depthCounts, ci.nshape+1), found: found,
+		progress: progress}
+}
+
+// search fills one empty cell at a time, depth first, starting from the
+// placements already on the stack, and calls found with the stack of
+// placements for every solution.  The used mask has bit i set when shape
+// i has been placed.
+func (sr *searcher) search(filled mask.Bits, used uint64,
+	stack []cellPlacement) {
+
+	ci := sr.ci
+	counts := sr.counts
+	var step func(filled mask.Bits, used uint64)
+	step = func(filled mask.Bits, used uint64) {
+		if sr.progress.enough() {
+			return
+		}
+		counts[len(stack)].received++
+		if sr.nodes++; sr.nodes >= progressBatch {
+			sr.flush()
+		}
+		cell := ci.nextCell(filled, used, sr.strategy)
+		if cell < 0 {
+			if ci.complete(used, sr.optional) {
+				sr.found(stack)
+			}
+			return
+		}
+		dc := &counts[len(stack)]
+		for _, cp := range ci.cells[cell] {
+			if !ci.try(cp, filled, used, dc) {
+				continue
+			}
+			stack = append(stack, cp)
+			step(filled|cp.mask, used|1<<uint(cp.shape))
+			stack = stack[:len(stack)-1]
+		}
+	}
+	step(filled, used)
+}
+
+// flush adds the nodes searched since the last flush to the progress.
+func (sr *searcher) flush() {
+	atomic.AddInt64(&sr.progress.nodes, sr.nodes)
+	sr.nodes = 0
+}
+
+// try reports whether the placement can be added to the filled mask, and
+// counts the rule which rejected it if not.
+func (ci *cellIndex) try(cp cellPlacement, filled mask.Bits, used uint64,
+	dc *depthCounts) bool {
+
+	dc.tried++
+	if used&(1<<uint(cp.shape)) != 0 {
+		dc.used++
+		return false
+	}
+	if cp.mask&filled != 0 {
+		dc.collision++
+		return false
+	}
+	if !ci.fillable(filled|cp.mask, used|1<<uint(cp.shape)) {
+		dc.region++
+		return false
+	}
+	dc.emitted++
+	return true
+}
+
+// board returns a copy of the base Board with the placements on the stack.
+func (ci *cellIndex) board(base Board, stack []cellPlacement) Board {
+	for _, cp := range stack {
+		base = base.Place(ci.places[cp.shape][cp.place])
+	}
+	return base
+}
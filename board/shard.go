@@ -0,0 +1,49 @@
+// -*- tab-width: 4; -*-
+
+package board
+
+import (
+	"fmt"
+	"hash/fnv"
+)
+
+// Shard selects one of Count deterministic slices of a cell search, so
+// that the slices can be searched by separate processes.  Index counts
+// from 1 to Count.  The tasks at the top of the search tree are assigned
+// to shards by a hash of their placements, so every task belongs to
+// exactly one shard no matter which order the tasks are searched in.
+// The zero Shard searches everything.
+type Shard struct {
+	Index int
+	Count int
+}
+
+// ParseShard parses a shard in the form "i/K", as written by String.
+func ParseShard(text string) (Shard, error) {
+	var s Shard
+	var extra string
+	n, _ := fmt.Sscanf(text, "%d/%d%s", &s.Index, &s.Count, &extra)
+	if n != 2 || s.Count < 1 || s.Index < 1 || s.Index > s.Count {
+		return Shard{}, fmt.Errorf("invalid shard %q, expected i/K with "+
+			"1 <= i <= K", text)
+	}
+	return s, nil
+}
+
+func (s Shard) String() string {
+	if s.Count == 0 {
+		return "all"
+	}
+	return fmt.Sprintf("%d/%d", s.Index, s.Count)
+}
+
+// contains reports whether the task with the given path belongs to the
+// shard.
+func (s Shard) contains(path []int) bool {
+	if s.Count <= 1 {
+		return true
+	}
+	h := fnv.New32a()
+	fmt.Fprint(h, path)
+	return int(h.Sum32()%uint32(s.Count)) == s.Index-1
+}
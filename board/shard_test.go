@@ -0,0 +1,44 @@
+// -*- tab-width: 4; -*-
+
+package board
+
+import (
+	"testing"
+)
+
+func TestShards(t *testing.T) {
+
+	b := NewBoard(5, 5)
+	shapes := puzzleShapes()
+	total := countSolutions(t,
+		solve(t, b, shapes, Options{Strategy: FirstCell}), b.RegionMask())
+
+	seen := map[string]bool{}
+	sum := 0
+	for i := 1; i <= 3; i++ {
+		opts := Options{Strategy: FirstCell, Shard: Shard{i, 3}}
+		for sb := range solve(t, b, shapes, opts) {
+			key := sb.String()
+			if seen[key] {
+				t.Errorf("shard %v repeated a solution:\n%v", opts.Shard, sb)
+			}
+			seen[key] = true
+			sum++
+		}
+	}
+	if sum != total {
+		t.Errorf("shards found %d solutions in total, expected %d", sum, total)
+	}
+}
+
+func TestParseShard(t *testing.T) {
+	s, err := ParseShard("2/5")
+	if err != nil || s != (Shard{2, 5}) || s.String() != "2/5" {
+		t.Errorf("ParseShard(\"2/5\") = %v, %v", s, err)
+	}
+	for _, text := range []string{"0/5", "6/5", "1/0", "1", "1/2x", "a/b"} {
+		if _, err := ParseShard(text); err == nil {
+			t.Errorf("ParseShard(%q) should fail", text)
+		}
+	}
+}
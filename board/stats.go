@@ -0,0 +1,220 @@
+// -*- tab-width: 4; -*-
+
+package board
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+
+	"github.com/garyjg/shapepuzzle/shape"
+)
+
+// Names of the pruning rules counted in StageStats.Rejected.  Placements
+// rejected by a gap template are counted under "gap#" and the ID of the
+// template shape from GapShapes.
+const (
+	// RuleCollision rejects a placement which overlaps a filled cell.
+	RuleCollision = "collision"
+	// RulePrepared rejects a placement which leaves an unfillable gap even
+	// on the empty board, before any boards are received.
+	RulePrepared = "prepared"
+	// RuleUsed skips placements of shapes which are already on the board.
+	RuleUsed = "shape used"
+	// RuleRegion rejects a placement which leaves an empty region with an
+	// area no combination of the remaining shapes could cover.
+	RuleRegion = "region area"
+)
+
+// Stats reports the work done by a search, one StageStats for each stage.
+// A stage of the Pipeline strategy is the goroutine placing one shape,
+// while a stage of a cell search is one level of the search tree.  Stats
+// passed in Options are complete once the solution Channel is closed.
+type Stats struct {
+	Strategy  string        `json:"strategy"`
+	Workers   int           `json:"workers"`
+	Solutions int64         `json:"solutions"`
+	Elapsed   time.Duration `json:"elapsed_ns"`
+	Stages    []*StageStats `json:"stages"`
+
+	began time.Time
+}
+
+// StageStats counts the boards received by one stage of a search, the
+// placements it tried on those boards, the placements it rejected by each
+// pruning rule, and the boards it emitted to the next stage.  PeakQueue is
+// the largest number of boards waiting in the stage's output channel, and
+// Time is the time the stage spent working rather than waiting for boards.
+// Cell searches have no channels between stages, so they leave PeakQueue
+// and Time zero and WriteTable leaves those columns out.
+type StageStats struct {
+	Stage     int              `json:"stage"`
+	ShapeID   int              `json:"shape,omitempty"`
+	Received  int64            `json:"received"`
+	Tried     int64            `json:"tried"`
+	Rejected  map[string]int64 `json:"rejected"`
+	Emitted   int64            `json:"emitted"`
+	PeakQueue int              `json:"peak_queue"`
+	Time      time.Duration    `json:"time_ns"`
+
+	gaps     map[int]int64
+	progress *progressCounter
+	last     bool
+	done     func()
+}
+
+func newStageStats(stage int, s shape.Shape) *StageStats {
+	return &StageStats{Stage: stage, ShapeID: s.ID(),
+		Rejected: map[string]int64{}, gaps: map[int]int64{}}
+}
+
+func (st *StageStats) reject(rule string) {
+	st.Rejected[rule]++
+}
+
+// rejectGap counts a rejection by a gap template by the template's ID, so
+// that the rule name only needs to be formatted once per template.
+func (st *StageStats) rejectGap(template shape.Shape) {
+	st.gaps[template.ID()]++
+}
+
+// emit passes a board to the next stage and tracks the peak occupancy of
+// the channel.
+func (st *StageStats) emit(bc Channel, b Board) {
+	bc <- b
+	st.Emitted++
+	if n := len(bc); n > st.PeakQueue {
+		st.PeakQueue = n
+	}
+	if pc := st.progress; pc != nil {
+		atomic.AddInt64(&pc.nodes, 1)
+		if st.Stage == 0 {
+			atomic.AddInt64(&pc.firstTotal, 1)
+		}
+		if st.last {
+			atomic.AddInt64(&pc.solutions, 1)
+		}
+	}
+}
+
+// received counts a board which the stage has finished with.  Boards
+// finished by the second stage are the first-level placements done.
+func (st *StageStats) received() {
+	if st.progress != nil && st.Stage == 1 {
+		atomic.AddInt64(&st.progress.firstDone, 1)
+	}
+}
+
+// finish folds the gap template counts into Rejected once the stage is
+// done.  The last stage also completes the totals in Stats.
+func (st *StageStats) finish() {
+	for id, n := range st.gaps {
+		st.Rejected[fmt.Sprintf("gap#%d", id)] += n
+	}
+	st.gaps = map[int]int64{}
+	if st.done != nil {
+		st.done()
+	}
+	if st.last && st.progress != nil {
+		st.progress.stop()
+	}
+}
+
+// start resets the Stats for a new search with the given strategy, number
+// of goroutines and stages.  The last stage's emitted boards are the
+// solutions.
+func (stats *Stats) start(strategy Strategy, workers int,
+	stages []*StageStats) {
+	stats.Strategy = strategy.String()
+	stats.Workers = workers
+	stats.Solutions = 0
+	stats.Elapsed = 0
+	stats.Stages = stages
+	stats.began = time.Now()
+	if len(stages) > 0 {
+		last := stages[len(stages)-1]
+		last.done = func() {
+			stats.Solutions = last.Emitted
+			stats.Elapsed = time.Since(stats.began)
+		}
+	}
+}
+
+// WriteTable prints the Stats as a text table, one row per stage, with a
+// column for each pruning rule which rejected any placements.  The peak
+// queue and time columns are only printed for the Pipeline strategy.
+func (stats *Stats) WriteTable(w io.Writer) error {
+
+	rules := map[string]bool{}
+	for _, st := range stats.Stages {
+		for rule := range st.Rejected {
+			rules[rule] = true
+		}
+	}
+	names := make([]string, 0, len(rules))
+	for rule := range rules {
+		names = append(names, rule)
+	}
+	sort.Strings(names)
+
+	timed := stats.Strategy == Pipeline.String()
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "stage\tshape\treceived\ttried\t%s\temitted\t",
+		strings.Join(names, "\t"))
+	if timed {
+		fmt.Fprint(tw, "peak queue\ttime\t")
+	}
+	fmt.Fprintln(tw)
+	for _, st := range stats.Stages {
+		fmt.Fprintf(tw, "%d\t%d\t%d\t%d\t", st.Stage, st.ShapeID, st.Received,
+			st.Tried)
+		for _, rule := range names {
+			fmt.Fprintf(tw, "%d\t", st.Rejected[rule])
+		}
+		fmt.Fprintf(tw, "%d\t", st.Emitted)
+		if timed {
+			fmt.Fprintf(tw, "%d\t%v\t", st.PeakQueue,
+				st.Time.Round(time.Microsecond))
+		}
+		fmt.Fprintln(tw)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "%s search, %d workers: %d solutions in %v\n",
+		stats.Strategy, stats.Workers, stats.Solutions,
+		stats.Elapsed.Round(time.Millisecond))
+	return err
+}
+
+// depthCounts accumulates the counters for one level of a cell search.
+// Each worker keeps its own, so the innermost loops need no maps or locks.
+type depthCounts struct {
+	received, tried, emitted int64
+	used, collision, region  int64
+}
+
+// cellStats merges the counters from every worker into stages of Stats.
+func cellStats(counts [][]depthCounts) []*StageStats {
+	stages := []*StageStats{}
+	for _, worker := range counts {
+		for depth, dc := range worker {
+			for len(stages) <= depth {
+				stages = append(stages, &StageStats{Stage: len(stages),
+					Rejected: map[string]int64{}})
+			}
+			st := stages[depth]
+			st.Received += dc.received
+			st.Tried += dc.tried
+			st.Emitted += dc.emitted
+			st.Rejected[RuleUsed] += dc.used
+			st.Rejected[RuleCollision] += dc.collision
+			st.Rejected[RuleRegion] += dc.region
+		}
+	}
+	return stages
+}
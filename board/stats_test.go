@@ -0,0 +1,65 @@
+// -*- tab-width: 4; -*-
+
+package board
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func checkStats(t *testing.T, stats *Stats, nfound int) {
+	if stats.Solutions != int64(nfound) {
+		t.Errorf("%s stats count %d solutions, search found %d",
+			stats.Strategy, stats.Solutions, nfound)
+	}
+	for i := 1; i < len(stats.Stages); i++ {
+		prev, st := stats.Stages[i-1], stats.Stages[i]
+		if prev.Emitted != st.Received {
+			t.Errorf("%s stage %d emitted %d boards but stage %d received %d",
+				stats.Strategy, i-1, prev.Emitted, i, st.Received)
+		}
+		var rejected int64
+		for _, n := range st.Rejected {
+			rejected += n
+		}
+		if stats.Strategy != Pipeline.String() &&
+			st.Tried != rejected+st.Emitted {
+			t.Errorf("%s stage %d tried %d placements, but %d rejected "+
+				"and %d emitted", stats.Strategy, i, st.Tried, rejected,
+				st.Emitted)
+		}
+	}
+	var buf bytes.Buffer
+	if err := stats.WriteTable(&buf); err != nil {
+		t.Errorf("WriteTable failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), RuleCollision) {
+		t.Errorf("WriteTable has no collision column:\n%s", buf.String())
+	}
+	if timed := strings.Contains(buf.String(), "peak queue"); timed !=
+		(stats.Strategy == Pipeline.String()) {
+		t.Errorf("%s WriteTable has the wrong time columns:\n%s",
+			stats.Strategy, buf.String())
+	}
+}
+
+func TestStats(t *testing.T) {
+
+	b := NewBoard(5, 5)
+	shapes := puzzleShapes()
+
+	for _, opts := range []Options{
+		{Strategy: Pipeline},
+		{Strategy: FirstCell},
+		{Strategy: ConstrainedCell, Workers: 3},
+	} {
+		opts.Stats = &Stats{}
+		n := countSolutions(t, solve(t, b, shapes, opts), b.RegionMask())
+		checkStats(t, opts.Stats, n)
+		if opts.Strategy == Pipeline && len(opts.Stats.Stages) != len(shapes) {
+			t.Errorf("pipeline stats have %d stages for %d shapes",
+				len(opts.Stats.Stages), len(shapes))
+		}
+	}
+}
@@ -0,0 +1,88 @@
+// -*- tab-width: 4; -*-
+
+package board
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Subset is a set of shapes, given by their IDs in increasing order, and
+// the number of solutions which place exactly those shapes.
+type Subset struct {
+	IDs       []int `json:"ids"`
+	Solutions int   `json:"solutions"`
+}
+
+// SubsetCounter tallies the solutions of a search with optional shapes by
+// the subset of shapes each one places.  The zero value is ready to use.
+type SubsetCounter struct {
+	subsets map[string]*Subset
+}
+
+// Add counts the solution under the set of shapes placed on it.  Holes,
+// which are placed as shape 0, are not part of the set.
+func (sc *SubsetCounter) Add(b Board) {
+	ids := []int{}
+	for _, s := range b.placements {
+		ids = append(ids, s.ID())
+	}
+	sc.add(ids)
+}
+
+// AddCheckpoint counts every solution recorded in a Checkpoint of a search
+// of the board, as Add would count them, so that the solutions found
+// before a search was resumed are tallied too.
+func (sc *SubsetCounter) AddCheckpoint(b Board, cp *Checkpoint) {
+	for _, sol := range cp.Solutions {
+		ids := []int{}
+		for _, s := range b.placements {
+			ids = append(ids, s.ID())
+		}
+		for _, p := range sol {
+			ids = append(ids, p.ID)
+		}
+		sc.add(ids)
+	}
+}
+
+// add counts a solution which places the shapes with the given IDs.
+func (sc *SubsetCounter) add(placed []int) {
+	ids := []int{}
+	for _, id := range placed {
+		if id != 0 {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+	key := fmt.Sprint(ids)
+	if sc.subsets == nil {
+		sc.subsets = map[string]*Subset{}
+	}
+	if sc.subsets[key] == nil {
+		sc.subsets[key] = &Subset{IDs: ids}
+	}
+	sc.subsets[key].Solutions++
+}
+
+// Subsets returns every subset counted so far, those with the fewest
+// shapes first and then in order of their IDs.
+func (sc *SubsetCounter) Subsets() []Subset {
+	subsets := []Subset{}
+	for _, s := range sc.subsets {
+		subsets = append(subsets, *s)
+	}
+	sort.Slice(subsets, func(i, j int) bool {
+		a, b := subsets[i].IDs, subsets[j].IDs
+		if len(a) != len(b) {
+			return len(a) < len(b)
+		}
+		for k := range a {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return false
+	})
+	return subsets
+}
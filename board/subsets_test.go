@@ -0,0 +1,53 @@
+// -*- tab-width: 4; -*-
+
+package board
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/garyjg/shapepuzzle/shape"
+)
+
+func TestOptionalShapes(t *testing.T) {
+
+	// A row of four cells is filled by the two dominoes or by the tromino
+	// and the monomino, each in two orders.
+	shapes := shape.MakeShapes([][][]int{
+		{{1, 1}}, {{1, 1}}, {{1, 1, 1}}, {{1}}})
+	b := NewBoard(1, 4)
+	for _, opts := range []Options{
+		{Strategy: FirstCell, Optional: true},
+		{Strategy: ConstrainedCell, Optional: true, Workers: 4}} {
+		sc := &SubsetCounter{}
+		for sol := range solve(t, b, shapes, opts) {
+			if sol.Mask() != b.RegionMask() {
+				t.Errorf("Solution does not fill the board:\n%v", sol)
+			}
+			sc.Add(sol)
+		}
+		got := fmt.Sprint(sc.Subsets())
+		if want := "[{[1 2] 2} {[3 4] 2}]"; got != want {
+			t.Errorf("%v found subsets %s, want %s", opts.Strategy, got, want)
+		}
+	}
+
+	// A search resumed from a checkpoint counts the solutions found before
+	// it stopped as well.
+	opts := Options{Strategy: FirstCell, Optional: true,
+		Checkpoint: &Checkpoint{}}
+	n := countSolutions(t, solve(t, b, shapes, opts), b.RegionMask())
+	sc := &SubsetCounter{}
+	sc.AddCheckpoint(b, opts.Checkpoint)
+	if got := fmt.Sprint(sc.Subsets()); n != 4 ||
+		got != "[{[1 2] 2} {[3 4] 2}]" {
+		t.Errorf("checkpoint of %d solutions has subsets %s", n, got)
+	}
+
+	// Without optional shapes nothing fits.
+	n = countSolutions(t, solve(t, b, shapes,
+		Options{Strategy: FirstCell}), b.RegionMask())
+	if n != 0 {
+		t.Errorf("Found %d solutions using every shape", n)
+	}
+}
@@ -0,0 +1,255 @@
+// -*- tab-width: 4; -*-
+
+package board
+
+import (
+	"fmt"
+
+	"github.com/garyjg/shapepuzzle/mask"
+	"github.com/garyjg/shapepuzzle/shape"
+)
+
+// Topology selects which edges of a Board join up, so that shapes placed
+// across them wrap around to the other side.
+type Topology int
+
+const (
+	// Plane is a flat board, where every edge is a border.
+	Plane Topology = iota
+	// Cylinder joins the left and right edges, so the columns wrap.
+	Cylinder
+	// Torus joins the top and bottom edges as well, so the rows and the
+	// columns wrap.
+	Torus
+)
+
+var topologyNames = []string{"plane", "cylinder", "torus"}
+
+func (t Topology) String() string {
+	if t < 0 || int(t) >= len(topologyNames) {
+		return fmt.Sprintf("Topology(%d)", int(t))
+	}
+	return topologyNames[t]
+}
+
+// ParseTopology returns the Topology with the given name, as returned by
+// the Topology String method.
+func ParseTopology(name string) (Topology, error) {
+	for i, n := range topologyNames {
+		if n == name {
+			return Topology(i), nil
+		}
+	}
+	return Plane, fmt.Errorf("unknown board topology: %s", name)
+}
+
+// WithTopology returns a copy of the Board with the given topology.
+func (b Board) WithTopology(t Topology) Board {
+	b.topology = t
+	return b
+}
+
+// Topology returns the topology of the Board.
+func (b Board) Topology() Topology {
+	return b.topology
+}
+
+// wrapRows and wrapCols return true if the rows or columns of the Board
+// wrap around.
+func (b Board) wrapRows() bool {
+	return b.topology == Torus
+}
+
+func (b Board) wrapCols() bool {
+	return b.topology == Cylinder || b.topology == Torus
+}
+
+// wrap translates the cells of m by r rows and c columns, wrapping them
+// around the edges which join.  Cells which move past an edge which does
+// not join are dropped if clip is true, or else the translation fails.  It
+// also fails if two cells wrap onto the same cell, when the board is
+// narrower than the cells.
+func (b Board) wrap(m mask.Bits, r int, c int, clip bool) (mask.Bits, bool) {
+	wrapped := mask.Bits(0)
+	for row, col := range m.Cells() {
+		row, col = row+r, col+c
+		if b.wrapRows() {
+			row = (row%b.nrows + b.nrows) % b.nrows
+		}
+		if b.wrapCols() {
+			col = (col%b.ncols + b.ncols) % b.ncols
+		}
+		if row < 0 || row >= b.nrows || col < 0 || col >= b.ncols {
+			if clip {
+				continue
+			}
+			return 0, false
+		}
+		cell := mask.Cell(row, col)
+		if wrapped&cell != 0 {
+			return 0, false
+		}
+		wrapped |= cell
+	}
+	return wrapped, true
+}
+
+// Translate returns the shape, starting from the upper left corner as the
+// Permutations do, moved by r rows and c columns on the Board and wrapped
+// around the edges which join, or false if it does not fit there.
+// A shape which wraps around an edge is made from its mask, so it has no
+// name.
+func (b Board) Translate(s shape.Shape, r int, c int) (shape.Shape, bool) {
+	if b.topology == Plane {
+		if r < 0 || c < 0 || r+s.NumRows() > b.nrows ||
+			c+s.NumCols() > b.ncols {
+			return shape.Shape{}, false
+		}
+		return s.Translate(r, c), true
+	}
+	m, ok := b.wrap(s.Mask(), r, c, false)
+	if !ok {
+		return shape.Shape{}, false
+	}
+	return shape.FromMask(s.ID(), m), true
+}
+
+// translations returns every permutation of the shape translated to every
+// position on the Board, in the order the permutations are tried and then
+// row by row.  If first is true only the positions needed for the first
+// shape of a Pipeline search are returned: on a plane, those in the upper
+// left quarter of the board, on a cylinder, only those in the first column
+// and upper half, and on a torus, only the upper left corner, since every
+// solution can be turned or slid round to put the first shape there.  The
+// positions are only cut down along the rows or columns where the cells
+// already filled, such as holes, are turned or slid onto themselves.
+//
+// A shape which wraps around an edge is made from its mask, so it has no
+// gaps.
+func (b Board) translations(s shape.Shape, first bool) []shape.Shape {
+
+	places := []shape.Shape{}
+	seen := map[mask.Bits]bool{}
+	cutRows, cutCols := b.symmetric()
+	for _, p := range s.Permutations() {
+		nrows, ncols := b.nrows-p.NumRows()+1, b.ncols-p.NumCols()+1
+		if b.wrapRows() {
+			nrows = b.nrows
+		}
+		if b.wrapCols() {
+			ncols = b.ncols
+		}
+		if first && cutRows {
+			nrows = min(nrows, b.nrows/2+1)
+			if b.wrapRows() {
+				nrows = 1
+			}
+		}
+		if first && cutCols {
+			ncols = min(ncols, b.ncols/2+1)
+			if b.wrapCols() {
+				ncols = 1
+			}
+		}
+		for r := 0; r < nrows; r++ {
+			for c := 0; c < ncols; c++ {
+				if b.topology == Plane {
+					places = append(places, p.Translate(r, c))
+					continue
+				}
+				m, ok := b.wrap(p.Mask(), r, c, false)
+				if ok && !seen[m] {
+					seen[m] = true
+					places = append(places, shape.FromMask(p.ID(), m))
+				}
+			}
+		}
+	}
+	return places
+}
+
+// symmetric reports whether the cells filled on the Board are the same
+// when the rows, and when the columns, are flipped over, or slid round by
+// one if they wrap.
+func (b Board) symmetric() (rows bool, cols bool) {
+	rows, cols = true, true
+	for r := 0; r < b.nrows; r++ {
+		for c := 0; c < b.ncols; c++ {
+			filled := b.mask&mask.Cell(r, c) != 0
+			fr, fc := b.nrows-1-r, b.ncols-1-c
+			if b.wrapRows() {
+				fr = (r + 1) % b.nrows
+			}
+			if b.wrapCols() {
+				fc = (c + 1) % b.ncols
+			}
+			if filled != (b.mask&mask.Cell(fr, c) != 0) {
+				rows = false
+			}
+			if filled != (b.mask&mask.Cell(r, fc) != 0) {
+				cols = false
+			}
+		}
+	}
+	return rows, cols
+}
+
+// wrapGapShapes places the gap templates at every position on a board
+// whose edges join, wrapping them around those edges and clipping them at
+// the others.
+func (b Board) wrapGapShapes(templates []shape.Shape) []shape.Shape {
+
+	shapes := []shape.Shape{}
+	for _, s := range templates {
+		rows := []int{}
+		for r := -1; r <= b.nrows-s.NumRows()+1; r++ {
+			rows = append(rows, r)
+		}
+		if b.wrapRows() {
+			rows = rows[:0]
+			for r := 0; r < b.nrows; r++ {
+				rows = append(rows, r)
+			}
+		}
+		for _, r := range rows {
+			for c := 0; c < b.ncols; c++ {
+				m, ok := b.wrap(s.Mask(), r, c, true)
+				if !ok {
+					continue
+				}
+				gaps, _ := b.wrap(s.GapMask(), r, c, true)
+				grid := make([][]int, b.nrows)
+				for i := range grid {
+					grid[i] = make([]int, b.ncols)
+				}
+				for row, col := range m.Cells() {
+					grid[row][col] = 1
+				}
+				for row, col := range gaps.Cells() {
+					grid[row][col] = 2
+				}
+				shapes = append(shapes, shape.NewShape(s.ID(), grid))
+			}
+		}
+	}
+	return shapes
+}
+
+// neighbors returns the cells next to the cells of m, across the edges
+// which join as well as within the board, and possibly cells outside the
+// board.
+func (b Board) neighbors(m mask.Bits) mask.Bits {
+	const firstCol = mask.Bits(0x8080808080808080)
+	const lastCol = mask.Bits(0x0101010101010101)
+	n := (m>>1)&^firstCol | (m<<1)&^lastCol | m>>8 | m<<8
+	if b.wrapCols() {
+		right := firstCol >> uint(b.ncols-1)
+		n |= (m&right)<<uint(b.ncols-1) | (m&firstCol)>>uint(b.ncols-1)
+	}
+	if b.wrapRows() {
+		top := mask.Bits(0xff) << 56
+		bottom := top >> uint(8*(b.nrows-1))
+		n |= (m&bottom)<<uint(8*(b.nrows-1)) | (m&top)>>uint(8*(b.nrows-1))
+	}
+	return n
+}
@@ -0,0 +1,83 @@
+// -*- tab-width: 4; -*-
+
+package board
+
+import (
+	"testing"
+
+	"github.com/garyjg/shapepuzzle/mask"
+	"github.com/garyjg/shapepuzzle/shape"
+)
+
+func TestCylinder(t *testing.T) {
+
+	// Two dominoes fill a row of four cells two ways on a plane, and two
+	// more on a cylinder with one of them wrapped around the edge.
+	dominoes := shape.MakeShapes([][][]int{{{1, 1}}, {{1, 1}}})
+	for topology, want := range map[Topology]int{Plane: 2, Cylinder: 4} {
+		b := NewBoard(1, 4).WithTopology(topology)
+		got := countSolutions(t,
+			solve(t, b, dominoes, Options{Strategy: FirstCell}),
+			b.RegionMask())
+		if got != want {
+			t.Errorf("%v found %d solutions, want %d", topology, got, want)
+		}
+	}
+}
+
+func TestTorus(t *testing.T) {
+
+	b := NewBoard(5, 5).WithTopology(Torus)
+	shapes := puzzleShapes()
+
+	// The pipeline puts the first shape in the corner, so it finds one
+	// solution for every 25 the cell search slides round the torus.
+	cell := countSolutions(t,
+		solve(t, b, shapes, Options{Strategy: ConstrainedCell}),
+		b.RegionMask())
+	pipeline := countSolutions(t,
+		solve(t, b, shapes, Options{Strategy: Pipeline}), b.RegionMask())
+	if pipeline == 0 || cell != 25*pipeline {
+		t.Errorf("Cell search found %d solutions and the pipeline %d",
+			cell, pipeline)
+	}
+	plane := countSolutions(t,
+		solve(t, NewBoard(5, 5), shapes, Options{Strategy: FirstCell}),
+		b.RegionMask())
+	if cell <= plane {
+		t.Errorf("Torus has %d solutions but the plane has %d", cell, plane)
+	}
+}
+
+func TestParseTopology(t *testing.T) {
+	for _, topology := range []Topology{Plane, Cylinder, Torus} {
+		got, err := ParseTopology(topology.String())
+		if err != nil || got != topology {
+			t.Errorf("ParseTopology(%q) = %v, %v", topology.String(), got, err)
+		}
+	}
+	if _, err := ParseTopology("klein"); err == nil {
+		t.Errorf("ParseTopology should reject unknown names")
+	}
+}
+
+func TestTorusReject(t *testing.T) {
+
+	// The cell inside the U is closed off on a plane, but open to the
+	// bottom row on a torus.
+	shapes := testShapes()
+	b := NewBoard(5, 5).WithTopology(Torus)
+	checkReject(t, NewBoard(5, 5).Place(shapes[1]), GapShapes(NewBoard(5, 5)),
+		true)
+	checkReject(t, b.Place(shapes[1]), GapShapes(b), false)
+
+	// The corner is closed off only by the cells across the edges.
+	m, _ := mask.ParseGrid(`
+		.#..#
+		#....
+		.....
+		.....
+		#....
+	`)
+	checkReject(t, b.Place(shape.FromMask(1, m)), GapShapes(b), true)
+}
@@ -0,0 +1,257 @@
+// -*- tab-width: 4; -*-
+
+// Package catalog holds well-known puzzles by name, each with the number
+// of solutions a complete cell search finds, so the solver can be checked
+// against them.
+//
+// The classic pentomino rectangles, 6x10, 5x12, 4x15 and 3x20, are bigger
+// than the 8x8 mask of the board package, so they are marked Rect and read
+// and solved by the rect package instead.
+package catalog
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/garyjg/shapepuzzle/puzzle"
+	"github.com/garyjg/shapepuzzle/rect"
+)
+
+// Entry is a named puzzle.  Solutions counts every solution, including
+// those which are rotations or reflections of others, as found by the
+// FirstCell and ConstrainedCell strategies.  The Pipeline strategy only
+// places the first shape in the upper left quarter of the board, so it
+// finds a different number.
+//
+// Rect is true for puzzles whose boards are too big for the board package.
+// Their definitions are read by RectPuzzle rather than Puzzle, and Solutions
+// is the number the rect package's cover search finds.
+type Entry struct {
+	Name        string
+	Description string
+	Solutions   int
+	Rect        bool
+	Definition  string
+}
+
+// Puzzle parses the Entry's definition.  It returns an error if the Entry
+// is a Rect puzzle.
+func (e Entry) Puzzle() (*puzzle.Puzzle, error) {
+	if e.Rect {
+		return nil, fmt.Errorf("%s is bigger than 8x8, so it can only be "+
+			"solved by the rect package", e.Name)
+	}
+	p, err := puzzle.Parse(strings.NewReader(e.Definition))
+	if err != nil {
+		return nil, err
+	}
+	if p.Name == "" {
+		p.Name = e.Name
+	}
+	return p, nil
+}
+
+// RectPuzzle parses the definition of a Rect Entry.
+func (e Entry) RectPuzzle() (*rect.Puzzle, error) {
+	p, err := rect.Parse(strings.NewReader(e.Definition))
+	if err != nil {
+		return nil, err
+	}
+	if p.Name == "" {
+		p.Name = e.Name
+	}
+	return p, nil
+}
+
+// Default is the name of the puzzle solved when no other is given.
+const Default = "shapepuzzle"
+
+// All returns every Entry in the catalog, ordered by name.
+func All() []Entry {
+	return entries
+}
+
+// Lookup returns the Entry with the given name.
+func Lookup(name string) (Entry, bool) {
+	for _, e := range entries {
+		if e.Name == name {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
+
+var entries = []Entry{{
+	Name:        "five",
+	Description: "five pieces on a 5x5 board, from the solver tests",
+	Solutions:   8,
+	Definition: `
+board 5 5
+piece A
+###
+#..
+#..
+#..
+piece B
+##.
+###
+piece C
+###
+.#.
+piece D
+..##
+###.
+piece E
+#.#
+###
+`}, {
+	Name:        "pentominoes3x20",
+	Description: "the 12 pentominoes on a 3x20 rectangle, 2 up to symmetry",
+	Solutions:   8,
+	Rect:        true,
+	Definition:  "board 3 20\n" + pentominoes,
+}, {
+	Name:        "pentominoes4x15",
+	Description: "the 12 pentominoes on a 4x15 rectangle, 368 up to symmetry",
+	Solutions:   1472,
+	Rect:        true,
+	Definition:  "board 4 15\n" + pentominoes,
+}, {
+	Name:        "pentominoes5x12",
+	Description: "the 12 pentominoes on a 5x12 rectangle, 1010 up to symmetry",
+	Solutions:   4040,
+	Rect:        true,
+	Definition:  "board 5 12\n" + pentominoes,
+}, {
+	Name:        "pentominoes6x10",
+	Description: "the 12 pentominoes on a 6x10 rectangle, 2339 up to symmetry",
+	Solutions:   9356,
+	Rect:        true,
+	Definition:  "board 6 10\n" + pentominoes,
+}, {
+	Name:        "scott",
+	Description: "the 12 pentominoes on 8x8 with a 2x2 hole in the centre",
+	Solutions:   520,
+	Definition: `
+board 8 8
+........
+........
+........
+...##...
+...##...
+........
+........
+........
+` + pentominoes}, {
+	Name:        "shapepuzzle",
+	Description: "the eleven pieces of the original 8x8 puzzle",
+	Solutions:   320,
+	Definition: `
+board 8 8
+piece A
+##.
+###
+piece B
+#.#
+###
+piece C
+#....
+#####
+piece D
+####
+#..#
+piece E
+###
+###
+.##
+piece F
+.#.
+###
+.#.
+piece G
+.#.
+.#.
+###
+piece H
+..##
+####
+piece I
+.##
+##.
+#..
+piece J
+#...
+#...
+#...
+####
+piece K
+#...
+####
+#...
+`}, {
+	Name:        "tetrominoes",
+	Description: "the five tetrominoes on a 4x5 board, which has no solution",
+	Solutions:   0,
+	Definition: `
+board 4 5
+piece I
+####
+piece L
+###
+#..
+piece O
+##
+##
+piece S
+.##
+##.
+piece T
+###
+.#.
+`}}
+
+// pentominoes is the twelve pentominoes, under their usual letters.
+const pentominoes = `
+piece F
+.##
+##.
+.#.
+piece I
+#####
+piece L
+####
+#...
+piece N
+##..
+.###
+piece P
+##
+##
+#.
+piece T
+###
+.#.
+.#.
+piece U
+#.#
+###
+piece V
+#..
+#..
+###
+piece W
+#..
+##.
+.##
+piece X
+.#.
+###
+.#.
+piece Y
+.#..
+####
+piece Z
+##.
+.#.
+.##
+`
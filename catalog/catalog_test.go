@@ -0,0 +1,76 @@
+// -*- tab-width: 4; -*-
+
+package catalog
+
+import (
+	"testing"
+
+	"github.com/garyjg/shapepuzzle/board"
+)
+
+func TestCatalog(t *testing.T) {
+	for _, e := range All() {
+		e := e
+		t.Run(e.Name, func(t *testing.T) {
+			var n int
+			if e.Rect {
+				n = countRect(t, e)
+			} else {
+				n = countCells(t, e)
+			}
+			if n != e.Solutions {
+				t.Errorf("%s has %d solutions, want %d", e.Name, n,
+					e.Solutions)
+			}
+		})
+	}
+	if _, ok := Lookup(Default); !ok {
+		t.Errorf("default puzzle %s is not in the catalog", Default)
+	}
+}
+
+// countCells counts the solutions of a catalog puzzle with a complete cell
+// search.
+func countCells(t *testing.T, e Entry) int {
+	p, err := e.Puzzle()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if testing.Short() && len(p.Shapes) > 11 {
+		t.Skip("skipping large puzzle in short mode")
+	}
+	opts := board.Options{Strategy: board.FirstCell}
+	solutions, err := p.Board.SolveOptions(p.Shapes, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	for range solutions {
+		n++
+	}
+	return n
+}
+
+// countRect counts the solutions of a Rect catalog puzzle with the cover
+// search of the rect package, checking that each fills the board.
+func countRect(t *testing.T, e Entry) int {
+	p, err := e.RectPuzzle()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if testing.Short() && len(p.Shapes) > 11 {
+		t.Skip("skipping large puzzle in short mode")
+	}
+	solutions, err := p.Board.Solve(p.Shapes, !p.OneSided)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	for b := range solutions {
+		if b.Mask() != b.Region() {
+			t.Fatalf("solution does not fill the board:\n%v", b)
+		}
+		n++
+	}
+	return n
+}
@@ -0,0 +1,179 @@
+// -*- tab-width: 4; -*-
+
+// Package cover searches for exact covers of up to 128 cells by placements
+// given as Sets of cells, whatever the shape of the grid the cells come
+// from.  The board package has its own search for square grids; this one
+// serves the grids which only need their placements listed, such as the
+// cubes of a polycube puzzle.  Grids of at most 64 cells can keep their
+// masks and convert them with FromMask and Set.Mask.  The boards of those
+// grids embed a Filling to keep their placements, and send their solutions
+// with Stream.
+package cover
+
+import (
+	"fmt"
+)
+
+// MaxPieces is the most pieces a Problem can have, as the search keeps the
+// pieces it has placed in the bits of a uint64.
+const MaxPieces = 64
+
+// Choice is one placement in a solution: the index of the piece in the
+// Problem, the index of the placement in that piece's list, and its cells.
+type Choice struct {
+	Piece int
+	Place int
+	Cells Set
+}
+
+// Problem is a set of cells to be covered exactly once by one placement of
+// every piece.  Pieces holds the cells of the placements of each piece.
+// Placements which cover cells outside the Region are ignored.
+//
+// Neighbors, when not nil, returns the cells next to any of the given
+// cells, so that the search can reject boards which leave a separate empty
+// region with an area no combination of the remaining pieces could cover.
+type Problem struct {
+	Region    Set
+	Pieces    [][]Set
+	Neighbors func(Set) Set
+}
+
+// index holds the placements which cover each cell, as in the cell search
+// of the board package.
+type index struct {
+	p     *Problem
+	areas []int
+	cells [MaxCells][]Choice
+}
+
+// Check returns an error if the Problem has more pieces than the search
+// can place.
+func (p *Problem) Check() error {
+	if len(p.Pieces) > MaxPieces {
+		return fmt.Errorf("%d pieces given, but a search can place at most %d",
+			len(p.Pieces), MaxPieces)
+	}
+	return nil
+}
+
+// Solve calls found with the placements of every solution, until found
+// returns false.  The slice passed to found is reused, so it must be
+// copied to be kept.  The search fills the empty cell with the fewest
+// placements left at each step.  It returns the error from Check without
+// searching if the Problem has too many pieces.
+func (p *Problem) Solve(found func([]Choice) bool) error {
+
+	if err := p.Check(); err != nil {
+		return err
+	}
+	ix := &index{p: p, areas: make([]int, len(p.Pieces))}
+	for i, places := range p.Pieces {
+		for j, m := range places {
+			ix.areas[i] = m.Count()
+			if !m.AndNot(p.Region).Empty() || m.Empty() {
+				continue
+			}
+			for rest := m; !rest.Empty(); {
+				cell := rest.First()
+				rest = rest.AndNot(Cell(cell))
+				ix.cells[cell] = append(ix.cells[cell], Choice{i, j, m})
+			}
+		}
+	}
+	all := uint64(1)<<uint(len(p.Pieces)) - 1
+	stack := make([]Choice, 0, len(p.Pieces))
+	var step func(filled Set, used uint64) bool
+	step = func(filled Set, used uint64) bool {
+		if filled == p.Region {
+			if used == all {
+				return found(stack)
+			}
+			return true
+		}
+		if !ix.fillable(filled, used) {
+			return true
+		}
+		cell := ix.bestCell(filled, used)
+		for _, ch := range ix.cells[cell] {
+			if used&(1<<uint(ch.Piece)) != 0 || !ch.Cells.And(filled).Empty() {
+				continue
+			}
+			stack = append(stack, ch)
+			more := step(filled.Or(ch.Cells), used|1<<uint(ch.Piece))
+			stack = stack[:len(stack)-1]
+			if !more {
+				return false
+			}
+		}
+		return true
+	}
+	step(Set{}, 0)
+	return nil
+}
+
+// Count returns the number of solutions, or the error from Solve.
+func (p *Problem) Count() (int, error) {
+	n := 0
+	err := p.Solve(func([]Choice) bool {
+		n++
+		return true
+	})
+	return n, err
+}
+
+// bestCell returns the empty cell covered by the fewest placements which
+// still fit.
+func (ix *index) bestCell(filled Set, used uint64) int {
+	best, nbest := -1, 0
+	for m := ix.p.Region.AndNot(filled); !m.Empty(); {
+		cell := m.First()
+		m = m.AndNot(Cell(cell))
+		n := 0
+		for _, ch := range ix.cells[cell] {
+			if used&(1<<uint(ch.Piece)) == 0 && ch.Cells.And(filled).Empty() {
+				n++
+			}
+		}
+		if best < 0 || n < nbest {
+			best, nbest = cell, n
+			if n == 0 {
+				break
+			}
+		}
+	}
+	return best
+}
+
+// fillable reports whether every separate empty region has an area which
+// some subset of the unused pieces could cover.  Without Neighbors there
+// are no regions, so it is always true.  The sums of the areas are kept in
+// a uint64, so regions of 64 cells or more are not checked.
+func (ix *index) fillable(filled Set, used uint64) bool {
+	if ix.p.Neighbors == nil {
+		return true
+	}
+	sums := uint64(1)
+	for i, area := range ix.areas {
+		if used&(1<<uint(i)) == 0 && area < 64 {
+			sums |= sums << uint(area)
+		}
+	}
+	empty := ix.p.Region.AndNot(filled)
+	for rest := empty; !rest.Empty(); {
+		// Grow the region from the cells it gained at the last step.
+		region := Cell(rest.First())
+		for edge := region; !edge.Empty(); {
+			edge = ix.p.Neighbors(edge).And(empty).AndNot(region)
+			region = region.Or(edge)
+		}
+		if region == empty {
+			break
+		}
+		rest = rest.AndNot(region)
+		if n := region.Count(); n < 64 && sums&(1<<uint(n)) == 0 {
+			return false
+		}
+	}
+	return true
+}
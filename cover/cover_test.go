@@ -0,0 +1,193 @@
+// -*- tab-width: 4; -*-
+
+package cover
+
+import (
+	"testing"
+
+	"github.com/garyjg/shapepuzzle/mask"
+)
+
+// dominoes returns the placements of a domino on a row of n cells.
+func dominoes(n int) []Set {
+	places := []Set{}
+	for i := 0; i+1 < n; i++ {
+		places = append(places, Cell(i).Or(Cell(i+1)))
+	}
+	return places
+}
+
+// count returns the number of solutions to p, failing the test if Count
+// returns an error.
+func count(t *testing.T, p *Problem) int {
+	n, err := p.Count()
+	if err != nil {
+		t.Fatalf("Count failed: %v", err)
+	}
+	return n
+}
+
+func TestCount(t *testing.T) {
+	// Two dominoes on a row of four cells go side by side in either order.
+	p := &Problem{Region: FirstCells(4),
+		Pieces: [][]Set{dominoes(4), dominoes(4)}}
+	if n := count(t, p); n != 2 {
+		t.Errorf("got %d solutions, expected 2", n)
+	}
+	// Three dominoes cannot cover five cells.
+	p = &Problem{Region: FirstCells(5),
+		Pieces: [][]Set{dominoes(5), dominoes(5), dominoes(5)}}
+	if n := count(t, p); n != 0 {
+		t.Errorf("got %d solutions, expected none", n)
+	}
+}
+
+func TestSolveStops(t *testing.T) {
+	p := &Problem{Region: FirstCells(4),
+		Pieces: [][]Set{dominoes(4), dominoes(4)}}
+	n := 0
+	err := p.Solve(func(choices []Choice) bool {
+		if len(choices) != 2 ||
+			choices[0].Cells.Or(choices[1].Cells) != p.Region {
+			t.Errorf("bad solution %v", choices)
+		}
+		n++
+		return false
+	})
+	if err != nil || n != 1 {
+		t.Errorf("search went on after %d solutions: %v", n, err)
+	}
+}
+
+func TestMaxPieces(t *testing.T) {
+	// Sixty-four monominoes fill 64 cells, but one more is too many.
+	monomino := []Set{}
+	for i := 0; i < 64; i++ {
+		monomino = append(monomino, Cell(i))
+	}
+	p := &Problem{Region: FirstCells(64)}
+	for i := 0; i < MaxPieces; i++ {
+		p.Pieces = append(p.Pieces, monomino)
+	}
+	n := 0
+	err := p.Solve(func(choices []Choice) bool {
+		n++
+		return false
+	})
+	if err != nil || n != 1 {
+		t.Errorf("%d monominoes found %d solutions: %v", MaxPieces, n, err)
+	}
+	p.Pieces = append(p.Pieces, monomino)
+	if _, err := p.Count(); err == nil {
+		t.Errorf("%d pieces gave no error", len(p.Pieces))
+	}
+}
+
+func TestNeighbors(t *testing.T) {
+	// Three dominoes and a monomino fill a row of seven cells with the
+	// monomino in one of four places and the dominoes in any order.  The
+	// pruning of regions must not lose any of them.
+	row := func(s Set) Set {
+		m := s.Mask()
+		return FromMask((m<<1 | m>>1) & mask.Bits(0xfe00000000000000))
+	}
+	monomino := []Set{}
+	for i := 0; i < 7; i++ {
+		monomino = append(monomino, Cell(i))
+	}
+	p := &Problem{Region: FirstCells(7),
+		Pieces: [][]Set{dominoes(7), dominoes(7), dominoes(7),
+			monomino}}
+	expect := count(t, p)
+	p.Neighbors = row
+	if n := count(t, p); n != expect || n != 24 {
+		t.Errorf("got %d solutions with neighbors, %d without, "+
+			"expected 24", n, expect)
+	}
+}
+
+func TestSet(t *testing.T) {
+	s := FirstCells(70)
+	if s.Count() != 70 || s[0] != ^uint64(0) || s[1] != 0xfc<<56 {
+		t.Errorf("FirstCells(70) is %x", s)
+	}
+	if s.First() != 0 || s.AndNot(FirstCells(66)).First() != 66 ||
+		(Set{}).First() != -1 {
+		t.Errorf("First gives the wrong cell")
+	}
+	if c := Cell(65); c[1] != 1<<62 || !c.And(s).Or(Cell(3)).AndNot(s).Empty() {
+		t.Errorf("Cell(65) is %x", c)
+	}
+	m := mask.Bits(0x8040201008040201)
+	if FromMask(m).Mask() != m || FromMask(m)[1] != 0 {
+		t.Errorf("FromMask(%v) is %x", m, FromMask(m))
+	}
+}
+
+func TestFilling(t *testing.T) {
+	f := Filling[Set]{}.Place(Placement[Set]{ID: 2, Mask: Cell(70)})
+	f = f.Place(Placement[Set]{ID: 5, Mask: Cell(1).Or(Cell(2))})
+	if f.Mask() != Cell(1).Or(Cell(2)).Or(Cell(70)) ||
+		len(f.Placements()) != 2 || f.ID(Cell(70)) != 2 ||
+		f.ID(Cell(2)) != 5 || f.ID(Cell(3)) != 0 {
+		t.Errorf("Set filling is %+v", f)
+	}
+	m := Filling[mask.Bits]{}.Place(Placement[mask.Bits]{ID: 3, Mask: 6})
+	if m.Mask() != 6 || m.ID(2) != 3 || m.ID(8) != 0 {
+		t.Errorf("mask filling is %+v", m)
+	}
+
+	// Two dominoes fill a row of four cells in two orders, and Stream
+	// sends the Filling made from each.
+	p := &Problem{Region: FirstCells(4),
+		Pieces: [][]Set{dominoes(4), dominoes(4)}}
+	bc, err := Stream(p, func(choices []Choice) Filling[Set] {
+		f := Filling[Set]{}
+		for _, ch := range choices {
+			f = f.Place(Placement[Set]{ID: ch.Piece + 1, Mask: ch.Cells})
+		}
+		return f
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	for f := range bc {
+		if f.Mask() != FirstCells(4) {
+			t.Errorf("solution %+v does not fill the region", f)
+		}
+		n++
+	}
+	if n != 2 {
+		t.Errorf("Stream sent %d solutions, expected 2", n)
+	}
+}
+
+func TestWideRegion(t *testing.T) {
+	// Dominoes fill a row of 100 cells in one way.  The region is too big
+	// for the areas to be checked, and then small enough.
+	row := func(s Set) Set {
+		n := Set{}
+		for rest := s; !rest.Empty(); {
+			i := rest.First()
+			rest = rest.AndNot(Cell(i))
+			if i > 0 {
+				n = n.Or(Cell(i - 1))
+			}
+			n = n.Or(Cell(i + 1))
+		}
+		return n.And(FirstCells(100)).AndNot(s)
+	}
+	p := &Problem{Region: FirstCells(100), Neighbors: row}
+	for i := 0; i < 50; i++ {
+		p.Pieces = append(p.Pieces, dominoes(100))
+	}
+	n := 0
+	err := p.Solve(func(choices []Choice) bool {
+		n++
+		return false
+	})
+	if err != nil || n != 1 {
+		t.Errorf("dominoes found %d solutions: %v", n, err)
+	}
+}
@@ -0,0 +1,93 @@
+// -*- tab-width: 4; -*-
+
+package cover
+
+import (
+	"github.com/garyjg/shapepuzzle/mask"
+)
+
+// Cells is the type a board keeps its cells in: a Set, or the mask of a
+// grid of at most 64 cells.
+type Cells interface {
+	Set | mask.Bits
+}
+
+// Placement is a piece at one position on a board, given by the piece's ID
+// and the cells it covers.
+type Placement[C Cells] struct {
+	ID   int
+	Mask C
+}
+
+// Filling is the placements made on a board so far and the cells they
+// fill.  The boards of the grids the cover search serves embed it, so that
+// they share its methods.
+type Filling[C Cells] struct {
+	mask       C
+	placements []Placement[C]
+}
+
+// Mask returns the cells filled so far.
+func (f Filling[C]) Mask() C {
+	return f.mask
+}
+
+// Placements returns the placements made on the board, in order.
+func (f Filling[C]) Placements() []Placement[C] {
+	return f.placements
+}
+
+// Place returns a copy of the Filling with the placement added.  It does
+// not check that the placement fits.
+func (f Filling[C]) Place(p Placement[C]) Filling[C] {
+	return Filling[C]{mask: or(f.mask, p.Mask),
+		placements: append(append([]Placement[C]{}, f.placements...), p)}
+}
+
+// ID returns the ID of the first placement which covers any of the cells,
+// or 0 if none does.
+func (f Filling[C]) ID(cells C) int {
+	for _, p := range f.placements {
+		if overlaps(p.Mask, cells) {
+			return p.ID
+		}
+	}
+	return 0
+}
+
+func or[C Cells](a C, b C) C {
+	switch a := any(a).(type) {
+	case Set:
+		return any(a.Or(any(b).(Set))).(C)
+	default:
+		return any(a.(mask.Bits) | any(b).(mask.Bits)).(C)
+	}
+}
+
+func overlaps[C Cells](a C, b C) bool {
+	switch a := any(a).(type) {
+	case Set:
+		return !a.And(any(b).(Set)).Empty()
+	default:
+		return a.(mask.Bits)&any(b).(mask.Bits) != 0
+	}
+}
+
+// Stream checks the Problem and then searches it on a new goroutine, which
+// sends the board made by solution from each solution's placements on the
+// channel it returns, and closes the channel when the search is done.  It
+// returns the error from Check without searching.
+func Stream[B any](p *Problem, solution func([]Choice) B) (chan B, error) {
+	if err := p.Check(); err != nil {
+		return nil, err
+	}
+	bc := make(chan B, 100)
+	go func() {
+		defer close(bc)
+		p.Solve(func(choices []Choice) bool {
+			bc <- solution(choices)
+			return true
+		})
+	}()
+	return bc, nil
+}
@@ -0,0 +1,83 @@
+// -*- tab-width: 4; -*-
+
+package cover
+
+import (
+	"math/bits"
+
+	"github.com/garyjg/shapepuzzle/mask"
+)
+
+// MaxCells is the most cells a Problem can have, one for each bit of a
+// Set.
+const MaxCells = 128
+
+// Set is a set of cells numbered from 0 to MaxCells-1.  Cell i is the bit
+// i%64 places below the top of word i/64, so the first 64 cells are laid
+// out as the bits of a mask.Bits, and a mask converts to a Set directly.
+type Set [2]uint64
+
+// FromMask returns the Set of the cells of m.
+func FromMask(m mask.Bits) Set {
+	return Set{uint64(m)}
+}
+
+// FirstCells returns the Set of cells 0 to n-1.
+func FirstCells(n int) Set {
+	s := Set{}
+	for w := range s {
+		if k := n - 64*w; k >= 64 {
+			s[w] = ^uint64(0)
+		} else if k > 0 {
+			s[w] = ^uint64(0) << uint(64-k)
+		}
+	}
+	return s
+}
+
+// Cell returns the Set of the single cell i.
+func Cell(i int) Set {
+	s := Set{}
+	s[i/64] = 1 << uint(63-i%64)
+	return s
+}
+
+// Mask returns the first 64 cells of the Set as a mask.
+func (s Set) Mask() mask.Bits {
+	return mask.Bits(s[0])
+}
+
+// Or returns the cells in either Set.
+func (s Set) Or(t Set) Set {
+	return Set{s[0] | t[0], s[1] | t[1]}
+}
+
+// And returns the cells in both Sets.
+func (s Set) And(t Set) Set {
+	return Set{s[0] & t[0], s[1] & t[1]}
+}
+
+// AndNot returns the cells of s which are not in t.
+func (s Set) AndNot(t Set) Set {
+	return Set{s[0] &^ t[0], s[1] &^ t[1]}
+}
+
+// Empty reports whether the Set has no cells.
+func (s Set) Empty() bool {
+	return s[0]|s[1] == 0
+}
+
+// Count returns the number of cells in the Set.
+func (s Set) Count() int {
+	return bits.OnesCount64(s[0]) + bits.OnesCount64(s[1])
+}
+
+// First returns the lowest numbered cell in the Set, or -1 if it is empty.
+func (s Set) First() int {
+	for w, word := range s {
+		if word != 0 {
+			return 64*w + bits.LeadingZeros64(word)
+		}
+	}
+	return -1
+}
@@ -0,0 +1,205 @@
+// -*- tab-width: 4; -*-
+
+package cube
+
+import (
+	"fmt"
+
+	"github.com/garyjg/shapepuzzle/cover"
+	"github.com/garyjg/shapepuzzle/mask"
+)
+
+// column, row and layer return the masks of every cell in the given
+// column, row or layer of the 4x4x4 space.
+func column(c int) mask.Bits {
+	m := mask.Bits(0)
+	for i := 0; i < 16; i++ {
+		m |= mask.FirstBit() >> uint(i*4+c)
+	}
+	return m
+}
+
+func row(r int) mask.Bits {
+	m := mask.Bits(0)
+	for l := 0; l < Size; l++ {
+		m |= mask.Bits(0xf) << uint(60-l*16-r*4)
+	}
+	return m
+}
+
+func layer(l int) mask.Bits {
+	return mask.Bits(0xffff) << uint(48-l*16)
+}
+
+// Translate moves the cells of m by the given number of layers, rows and
+// columns.  Cells moved out of the 4x4x4 space are dropped, rather than
+// wrapping into the next row or layer.
+func Translate(m mask.Bits, layers, rows, cols int) mask.Bits {
+	shift := func(m mask.Bits, n int) mask.Bits {
+		if n >= 0 {
+			return m >> uint(n)
+		}
+		return m << uint(-n)
+	}
+	for i := 0; i < Size; i++ {
+		if i+cols < 0 || i+cols >= Size {
+			m &^= column(i)
+		}
+		if i+rows < 0 || i+rows >= Size {
+			m &^= row(i)
+		}
+		if i+layers < 0 || i+layers >= Size {
+			m &^= layer(i)
+		}
+	}
+	return shift(m, layers*16+rows*4+cols)
+}
+
+// Neighbors returns the cells which share a face with any cell of m and
+// are not in m themselves.
+func Neighbors(m mask.Bits) mask.Bits {
+	n := Translate(m, 0, 0, 1) | Translate(m, 0, 0, -1) |
+		Translate(m, 0, 1, 0) | Translate(m, 0, -1, 0) |
+		Translate(m, 1, 0, 0) | Translate(m, -1, 0, 0)
+	return n &^ m
+}
+
+// connected returns true if every cell of m can be reached from every
+// other through cells of m.
+func connected(m mask.Bits) bool {
+	if m == 0 {
+		return true
+	}
+	region := m & -m
+	for {
+		next := region | Neighbors(region)&m
+		if next == region {
+			return region == m
+		}
+		region = next
+	}
+}
+
+// Placement is a shape at one position in a box, given by the shape's ID
+// and the mask of the cells it covers.
+type Placement = cover.Placement[mask.Bits]
+
+type filling = cover.Filling[mask.Bits]
+
+// Board is a box of layers, rows and columns, up to 4x4x4, with the shape
+// placements made in it so far.
+type Board struct {
+	filling
+	nlayers int
+	nrows   int
+	ncols   int
+}
+
+// NewBoard makes an empty box with the given numbers of layers, rows and
+// columns, each from 1 to Size.
+func NewBoard(nlayers int, nrows int, ncols int) (Board, error) {
+	if nlayers < 1 || nlayers > Size || nrows < 1 || nrows > Size ||
+		ncols < 1 || ncols > Size {
+		return Board{}, fmt.Errorf("cube board is %dx%dx%d, but must be "+
+			"from 1x1x1 to %dx%dx%d", nlayers, nrows, ncols, Size, Size, Size)
+	}
+	return Board{nlayers: nlayers, nrows: nrows, ncols: ncols}, nil
+}
+
+// Size returns the number of layers, rows and columns in the box.
+func (b Board) Size() (layers, rows, cols int) {
+	return b.nlayers, b.nrows, b.ncols
+}
+
+// Region returns the mask of every cell in the box.
+func (b Board) Region() mask.Bits {
+	m := mask.Bits(0)
+	for l := 0; l < b.nlayers; l++ {
+		for r := 0; r < b.nrows; r++ {
+			for c := 0; c < b.ncols; c++ {
+				m |= Point{l, r, c}.Bit()
+			}
+		}
+	}
+	return m
+}
+
+// Place returns a copy of the Board with the placement added.  It does
+// not check that the placement fits.
+func (b Board) Place(p Placement) Board {
+	b.filling = b.filling.Place(p)
+	return b
+}
+
+// placementsOf returns every orientation of the Shape translated to every
+// position inside the Board which does not overlap the cells already
+// filled.
+func (b Board) placementsOf(s Shape, mirror bool) []mask.Bits {
+	places := []mask.Bits{}
+	for _, o := range s.Orientations(mirror) {
+		nl, nr, nc := o.Extent()
+		for l := 0; l+nl <= b.nlayers; l++ {
+			for r := 0; r+nr <= b.nrows; r++ {
+				for c := 0; c+nc <= b.ncols; c++ {
+					m := Translate(o.Mask(), l, r, c)
+					if m&b.Mask() == 0 {
+						places = append(places, m)
+					}
+				}
+			}
+		}
+	}
+	return places
+}
+
+// Channel is a channel for passing Board states.
+type Channel chan Board
+
+// Solve searches for every way to fill the rest of the box with one
+// placement of each shape, and sends each solution on the channel it
+// returns, which is closed when the search is done.  Shapes are turned by
+// the 24 rotations of the cube, and by their mirror images as well if
+// mirror is true.  Every solution is found, including those which are
+// rotations or reflections of others.  It returns an error, without
+// searching, if there are more shapes than the search can place.
+func (b Board) Solve(shapes []Shape, mirror bool) (Channel, error) {
+
+	region := b.Region()
+	p := &cover.Problem{Region: cover.FromMask(region &^ b.Mask()),
+		Neighbors: func(s cover.Set) cover.Set {
+			return cover.FromMask(Neighbors(s.Mask()) & region)
+		}}
+	for _, s := range shapes {
+		places := []cover.Set{}
+		for _, m := range b.placementsOf(s, mirror) {
+			places = append(places, cover.FromMask(m))
+		}
+		p.Pieces = append(p.Pieces, places)
+	}
+	bc, err := cover.Stream(p, func(choices []cover.Choice) Board {
+		nb := b
+		for _, ch := range choices {
+			nb = nb.Place(Placement{ID: shapes[ch.Piece].ID(),
+				Mask: ch.Cells.Mask()})
+		}
+		return nb
+	})
+	return bc, err
+}
+
+// String draws the Board a layer at a time, with the ID of the shape in
+// each cell, like the board package draws a Board.
+func (b Board) String() string {
+	buf := ""
+	for l := 0; l < b.nlayers; l++ {
+		buf += fmt.Sprintf("Layer %d\n", l+1)
+		for r := 0; r < b.nrows; r++ {
+			buf += "["
+			for c := 0; c < b.ncols; c++ {
+				buf += fmt.Sprintf(" %2d", b.ID(Point{l, r, c}.Bit()))
+			}
+			buf += "]\n"
+		}
+	}
+	return buf
+}
@@ -0,0 +1,165 @@
+// -*- tab-width: 4; -*-
+
+package cube
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/garyjg/shapepuzzle/mask"
+)
+
+func TestTranslate(t *testing.T) {
+	corner := Point{0, 0, 0}.Bit()
+	tests := []struct {
+		l, r, c int
+		expect  mask.Bits
+	}{
+		{0, 0, 0, corner},
+		{1, 2, 3, Point{1, 2, 3}.Bit()},
+		{3, 3, 3, Point{3, 3, 3}.Bit()},
+		{0, 0, 4, 0},
+		{0, 4, 0, 0},
+		{4, 0, 0, 0},
+		{-1, 0, 0, 0},
+	}
+	for _, test := range tests {
+		got := Translate(corner, test.l, test.r, test.c)
+		if got != test.expect {
+			t.Errorf("translate (%d,%d,%d) got %v, expected %v",
+				test.l, test.r, test.c, got, test.expect)
+		}
+	}
+	// A row of four cells moved one column loses its last cell, rather
+	// than wrapping into the next row.
+	line := Point{0, 0, 0}.Bit() | Point{0, 0, 1}.Bit() |
+		Point{0, 0, 2}.Bit() | Point{0, 0, 3}.Bit()
+	expect := Point{0, 0, 1}.Bit() | Point{0, 0, 2}.Bit() | Point{0, 0, 3}.Bit()
+	if got := Translate(line, 0, 0, 1); got != expect {
+		t.Errorf("translate row got %v, expected %v", got, expect)
+	}
+}
+
+func TestNeighbors(t *testing.T) {
+	tests := []struct {
+		p     Point
+		count int
+	}{
+		{Point{0, 0, 0}, 3},
+		{Point{0, 0, 3}, 3},
+		{Point{1, 1, 3}, 5},
+		{Point{1, 2, 1}, 6},
+		{Point{3, 3, 3}, 3},
+	}
+	for _, test := range tests {
+		if got := Neighbors(test.p.Bit()).Count(); got != test.count {
+			t.Errorf("%v has %d neighbors, expected %d", test.p, got,
+				test.count)
+		}
+	}
+}
+
+func TestOrientations(t *testing.T) {
+	p, err := Parse(strings.NewReader(Soma))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The screws are mirror images, so they have twice the orientations
+	// when reflections are allowed.
+	expect := map[string][2]int{"V": {12, 12}, "L": {24, 24},
+		"T": {12, 12}, "Z": {12, 12}, "A": {12, 24}, "B": {12, 24},
+		"P": {8, 8}}
+	for _, s := range p.Shapes {
+		got := [2]int{len(s.Orientations(false)), len(s.Orientations(true))}
+		if got != expect[s.Name()] {
+			t.Errorf("piece %s has %v orientations, expected %v",
+				s.Name(), got, expect[s.Name()])
+		}
+	}
+}
+
+func TestNewShape(t *testing.T) {
+	if _, err := NewShape(1, [][][]int{{{1, 0}}, {{0, 1}}}); err == nil {
+		t.Errorf("diagonal cubes should not be connected")
+	}
+	if _, err := NewShape(1, [][][]int{{{1, 1, 1, 1, 1}}}); err == nil {
+		t.Errorf("five cells in a row should be too long")
+	}
+	if _, err := NewShape(1, [][][]int{{{0}}}); err == nil {
+		t.Errorf("shape with no cells should be an error")
+	}
+}
+
+func countSolutions(t *testing.T, definition string) int {
+	p, err := Parse(strings.NewReader(definition))
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	bc, err := p.Board.Solve(p.Shapes, p.Mirror)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for b := range bc {
+		if b.Mask() != b.Region() {
+			t.Errorf("solution does not fill the box:\n%v", b)
+		}
+		n++
+	}
+	return n
+}
+
+func TestSoma(t *testing.T) {
+	// 240 solutions, each in the 24 rotations and 24 reflections of the
+	// cube.
+	if n := countSolutions(t, Soma); n != 11520 {
+		t.Errorf("soma cube has %d solutions, expected 11520", n)
+	}
+}
+
+func TestPlates(t *testing.T) {
+	// Four 4x4 plates fill the 4x4x4 cube stacked along any of the three
+	// axes, in any order.
+	plate := "piece %s\n####\n####\n####\n####\n"
+	def := "board 4 4 4\n"
+	for _, name := range []string{"A", "B", "C", "D"} {
+		def += strings.Replace(plate, "%s", name, 1)
+	}
+	if n := countSolutions(t, def); n != 72 {
+		t.Errorf("plates have %d solutions, expected 72", n)
+	}
+}
+
+func TestLayerRows(t *testing.T) {
+	// A grid line of single cells in three layers is not a comment, even
+	// though it starts with "# ".
+	if n := countSolutions(t, "board 3 1 1\npiece I\n# # #\n"); n != 1 {
+		t.Errorf("column of three layers has %d solutions, expected 1", n)
+	}
+}
+
+func TestString(t *testing.T) {
+	b, err := NewBoard(2, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b = b.Place(Placement{ID: 3,
+		Mask: Point{0, 0, 0}.Bit() | Point{1, 0, 0}.Bit()})
+	expect := "Layer 1\n[  3  0]\nLayer 2\n[  3  0]\n"
+	if got := b.String(); got != expect {
+		t.Errorf("got board\n%s, expected\n%s", got, expect)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []string{
+		"board 5 1 1\npiece A\n#\n",
+		"board 2 2\npiece A\n#\n",
+		"board 2 2 2\npiece A\n#. .#\n",
+	}
+	for _, test := range tests {
+		if _, err := Parse(strings.NewReader(test)); err == nil {
+			t.Errorf("parsing %q should fail", test)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+// -*- tab-width: 4; -*-
+
+package cube
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/garyjg/shapepuzzle/puzzle"
+)
+
+// Puzzle is a box and the polycubes which must fill it.  Mirror is true if
+// the pieces may be turned into their mirror images, which no real piece
+// can be, but which some puzzles allow to count the fillings of a shape.
+//
+// A cube puzzle file has the directives of the puzzle package, with the
+// board given as layers, rows and columns, and each grid line holding one
+// row of every layer, the layers separated by spaces:
+//
+//	# The V and L pieces of the Soma cube.
+//	name example
+//	board 3 3 3
+//	piece V
+//	##
+//	#.
+//	piece L
+//	### #..
+//
+// The grid after piece L is a row of three cells in the first layer and a
+// single cell above one end of it in the second.
+type Puzzle struct {
+	Name   string
+	Board  Board
+	Shapes []Shape
+	Mirror bool
+}
+
+// Load reads the cube puzzle definition in the file at path.
+func Load(path string) (*Puzzle, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	p, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return p, nil
+}
+
+// grammar describes cube puzzle files to puzzle.ParseDefinition.
+var grammar = puzzle.Grammar[Board, Shape]{
+	Extra: " ",
+	Flags: []string{"mirror"},
+	Board: parseBoard,
+	Piece: parsePiece,
+	Name:  Shape.WithName,
+}
+
+// Parse reads a cube puzzle definition.  Errors give the line number of
+// the directive at fault.
+func Parse(r io.Reader) (*Puzzle, error) {
+	d, err := puzzle.ParseDefinition(r, grammar)
+	if err != nil {
+		return nil, err
+	}
+	return &Puzzle{Name: d.Name, Board: d.Board, Shapes: d.Shapes,
+		Mirror: d.Flags["mirror"]}, nil
+}
+
+func parseBoard(args []string) (Board, error) {
+	if len(args) != 3 {
+		return Board{}, fmt.Errorf("board needs layers, rows and columns")
+	}
+	size := [3]int{}
+	for i, arg := range args {
+		n, err := strconv.Atoi(arg)
+		if err != nil {
+			return Board{}, fmt.Errorf("bad board size: %v", err)
+		}
+		size[i] = n
+	}
+	return NewBoard(size[0], size[1], size[2])
+}
+
+// parsePiece converts grid lines, each with one row of every layer, into
+// a Shape.
+func parsePiece(id int, rows []string) (Shape, error) {
+	grid := [][][]int{}
+	for r, text := range rows {
+		for l, cells := range strings.Fields(text) {
+			if l == len(grid) {
+				grid = append(grid, make([][]int, len(rows)))
+			}
+			grid[l][r] = make([]int, len(cells))
+			for c, cell := range cells {
+				if cell == '#' {
+					grid[l][r][c] = 1
+				}
+			}
+		}
+	}
+	return NewShape(id, grid)
+}
+
+// Soma is the Soma cube: seven pieces, each every non-convex shape of
+// three or four cubes, which fill a 3x3x3 box in 240 ways, not counting
+// rotations and reflections of the whole cube.
+const Soma = `
+name soma
+board 3 3 3
+piece V
+##
+#.
+piece L
+###
+#..
+piece T
+###
+.#.
+piece Z
+.##
+##.
+piece A
+## .#
+#. ..
+piece B
+## ..
+#. #.
+piece P
+## #.
+#. ..
+`
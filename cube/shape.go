@@ -0,0 +1,214 @@
+// -*- tab-width: 4; -*-
+
+// Package cube solves polycube puzzles, such as the Soma cube, on boxes of
+// up to 4x4x4 cells, which is exactly the 64 cells of a mask.Bits.
+//
+// Cells are numbered layer by layer, then row by row, from the most
+// significant bit, so that every layer takes 16 bits and every row 4 bits
+// whatever the size of the box: the cell at layer l, row r and column c is
+// bit l*16 + r*4 + c counting from mask.FirstBit().
+package cube
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/garyjg/shapepuzzle/mask"
+)
+
+// Size is the largest number of cells along each side of a box.
+const Size = 4
+
+// Point is the position of one cell: its layer, row and column.
+type Point struct {
+	L, R, C int
+}
+
+// Bit returns the mask with only the cell at p set.
+func (p Point) Bit() mask.Bits {
+	return mask.FirstBit() >> uint(p.L*16+p.R*4+p.C)
+}
+
+// Shape is a polycube: a set of cells with an ID and an optional name.
+// Its cells are kept in order, moved as close to the origin as they go.
+type Shape struct {
+	id    int
+	name  string
+	cells []Point
+}
+
+// NewShape makes a Shape from a grid of layers, each a grid of rows of
+// cells, where cells which are not 0 are part of the shape.  It returns an
+// error if the shape is empty, has a side longer than Size, or is not
+// connected face to face.
+func NewShape(id int, grid [][][]int) (Shape, error) {
+	cells := []Point{}
+	for l, layer := range grid {
+		for r, row := range layer {
+			for c, v := range row {
+				if v != 0 {
+					cells = append(cells, Point{l, r, c})
+				}
+			}
+		}
+	}
+	s := Shape{id: id, cells: normalize(cells)}
+	if len(cells) == 0 {
+		return s, fmt.Errorf("cube shape #%d has no cells", id)
+	}
+	if l, r, c := s.Extent(); l > Size || r > Size || c > Size {
+		return s, fmt.Errorf("cube shape #%d is %dx%dx%d, larger than "+
+			"%dx%dx%d", id, l, r, c, Size, Size, Size)
+	}
+	if !connected(s.Mask()) {
+		return s, fmt.Errorf("cube shape #%d is not connected", id)
+	}
+	return s, nil
+}
+
+// WithName returns a copy of the Shape with the given name.
+func (s Shape) WithName(name string) Shape {
+	s.name = name
+	return s
+}
+
+// ID returns the integer id of the Shape.
+func (s Shape) ID() int {
+	return s.id
+}
+
+// Name returns the name of the Shape, if it has one.
+func (s Shape) Name() string {
+	return s.name
+}
+
+// Cells returns the number of cells in the Shape.
+func (s Shape) Cells() int {
+	return len(s.cells)
+}
+
+// Extent returns the number of layers, rows and columns the Shape spans.
+func (s Shape) Extent() (layers, rows, cols int) {
+	for _, p := range s.cells {
+		layers = max(layers, p.L+1)
+		rows = max(rows, p.R+1)
+		cols = max(cols, p.C+1)
+	}
+	return layers, rows, cols
+}
+
+// Mask returns the mask of the Shape at the origin.
+func (s Shape) Mask() mask.Bits {
+	m := mask.Bits(0)
+	for _, p := range s.cells {
+		m |= p.Bit()
+	}
+	return m
+}
+
+func (s Shape) String() string {
+	return fmt.Sprintf("Cube shape #%d %s %v", s.id, s.name, s.cells)
+}
+
+// normalize moves the cells as close to the origin as they go and sorts
+// them, so two sets of the same cells are equal.
+func normalize(cells []Point) []Point {
+	if len(cells) == 0 {
+		return cells
+	}
+	lo := cells[0]
+	for _, p := range cells {
+		lo = Point{min(lo.L, p.L), min(lo.R, p.R), min(lo.C, p.C)}
+	}
+	out := make([]Point, len(cells))
+	for i, p := range cells {
+		out[i] = Point{p.L - lo.L, p.R - lo.R, p.C - lo.C}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if a.L != b.L {
+			return a.L < b.L
+		}
+		if a.R != b.R {
+			return a.R < b.R
+		}
+		return a.C < b.C
+	})
+	return out
+}
+
+// rotation maps a point by a signed permutation of its coordinates: axis
+// i of the result is axis perm[i] of the point, times sign[i].
+type rotation struct {
+	perm [3]int
+	sign [3]int
+}
+
+func (rot rotation) apply(p Point) Point {
+	v := [3]int{p.L, p.R, p.C}
+	return Point{rot.sign[0] * v[rot.perm[0]], rot.sign[1] * v[rot.perm[1]],
+		rot.sign[2] * v[rot.perm[2]]}
+}
+
+// proper returns true if the rotation keeps handedness, so it is a turn
+// of the cube rather than a mirror image.
+func (rot rotation) proper() bool {
+	// The parity of the permutation times the product of the signs.
+	parity := 1
+	for i := 0; i < 3; i++ {
+		for j := i + 1; j < 3; j++ {
+			if rot.perm[i] > rot.perm[j] {
+				parity = -parity
+			}
+		}
+	}
+	return parity*rot.sign[0]*rot.sign[1]*rot.sign[2] == 1
+}
+
+// rotations lists the 48 symmetries of the cube, the 24 proper rotations
+// first.
+var rotations = func() []rotation {
+	perms := [][3]int{{0, 1, 2}, {0, 2, 1}, {1, 0, 2}, {1, 2, 0}, {2, 0, 1},
+		{2, 1, 0}}
+	proper, mirror := []rotation{}, []rotation{}
+	for _, perm := range perms {
+		for signs := 0; signs < 8; signs++ {
+			rot := rotation{perm, [3]int{1, 1, 1}}
+			for i := 0; i < 3; i++ {
+				if signs&(1<<uint(i)) != 0 {
+					rot.sign[i] = -1
+				}
+			}
+			if rot.proper() {
+				proper = append(proper, rot)
+			} else {
+				mirror = append(mirror, rot)
+			}
+		}
+	}
+	return append(proper, mirror...)
+}()
+
+// Orientations returns the distinct orientations of the Shape under the
+// 24 rotations of the cube, or under all 48 symmetries, including the
+// mirror images, if mirror is true.
+func (s Shape) Orientations(mirror bool) []Shape {
+	n := 24
+	if mirror {
+		n = 48
+	}
+	seen := map[mask.Bits]bool{}
+	shapes := []Shape{}
+	for _, rot := range rotations[:n] {
+		cells := make([]Point, len(s.cells))
+		for i, p := range s.cells {
+			cells[i] = rot.apply(p)
+		}
+		o := Shape{id: s.id, name: s.name, cells: normalize(cells)}
+		if key := o.Mask(); !seen[key] {
+			seen[key] = true
+			shapes = append(shapes, o)
+		}
+	}
+	return shapes
+}
@@ -0,0 +1,44 @@
+// -*- tab-width: 4; -*-
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/garyjg/shapepuzzle/cube"
+)
+
+// solveCube solves the Soma cube, or the cube puzzle defined in a file,
+// and prints every solution a layer at a time.
+func solveCube(args []string) int {
+
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "usage: shapepuzzle cube soma|FILE")
+		return 2
+	}
+	var p *cube.Puzzle
+	var err error
+	if args[0] == "soma" {
+		p, err = cube.Parse(strings.NewReader(cube.Soma))
+	} else {
+		p, err = cube.Load(args[0])
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	bc, err := p.Board.Solve(p.Shapes, p.Mirror)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	nfound := 0
+	for b := range bc {
+		fmt.Printf("Solution found.\n%s\n", b)
+		nfound++
+	}
+	printCount(nfound)
+	return 0
+}
@@ -0,0 +1,453 @@
+// -*- tab-width: 4; -*-
+
+// Package generate designs new puzzles.  A board is cut into random
+// connected pieces, and the solver counts the ways those pieces fill the
+// board again.  Until the cut is the only solution, up to the symmetries of
+// the board, one cell at a time is moved from a piece to its neighbor and
+// the solutions are counted again.
+package generate
+
+import (
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"sort"
+	"strconv"
+
+	"github.com/garyjg/shapepuzzle/board"
+	"github.com/garyjg/shapepuzzle/mask"
+	"github.com/garyjg/shapepuzzle/puzzle"
+	"github.com/garyjg/shapepuzzle/shape"
+)
+
+// Options controls the puzzles Generate designs.
+type Options struct {
+	// Rows and Cols are the size of the board, up to 8x8.
+	Rows, Cols int
+
+	// MinSize and MaxSize limit the number of cells in each piece.
+	MinSize, MaxSize int
+
+	// Seed starts the random number generator, so the same options
+	// always design the same puzzle.
+	Seed int64
+
+	// Partitions is the number of times the board is cut up afresh, and
+	// Adjustments is the number of cells moved between pieces of each cut,
+	// before Generate gives up.  Zero means 100 of each.
+	Partitions  int
+	Adjustments int
+
+	// Logger is passed to the solver.  Nil means slog.Default().
+	Logger *slog.Logger
+}
+
+// pieceNames names the pieces of a generated puzzle in order.  Pieces
+// after the last name are named by their IDs.
+const pieceNames = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// Generate designs a puzzle whose pieces fill the board in only one way,
+// up to the symmetries of the board, and returns it with that solution.
+// No two pieces are the same shape, since swapping them would give a
+// second solution, so a cut with more pieces of one size than there are
+// shapes of that size is thrown away, and the pieces of a cut are
+// reshaped until they all differ before the solutions are counted.
+func Generate(opts Options) (*puzzle.Puzzle, board.Board, error) {
+
+	if opts.Rows < 1 || opts.Rows > 8 || opts.Cols < 1 || opts.Cols > 8 {
+		return nil, board.Board{}, fmt.Errorf("board is %dx%d, but must be "+
+			"from 1x1 to 8x8", opts.Rows, opts.Cols)
+	}
+	if opts.MinSize < 1 || opts.MaxSize < opts.MinSize ||
+		opts.MaxSize > opts.Rows*opts.Cols {
+		return nil, board.Board{}, fmt.Errorf("piece sizes %d to %d do not "+
+			"fit a %dx%d board", opts.MinSize, opts.MaxSize, opts.Rows,
+			opts.Cols)
+	}
+	partitions, adjustments := opts.Partitions, opts.Adjustments
+	if partitions == 0 {
+		partitions = 100
+	}
+	if adjustments == 0 {
+		adjustments = 100
+	}
+
+	g, err := newGenerator(opts)
+	if err != nil {
+		return nil, board.Board{}, err
+	}
+	for i := 0; i < partitions; i++ {
+		if !g.partition() || g.excess() > 0 {
+			continue
+		}
+		for j := 0; j < adjustments; j++ {
+			p, solution := g.pieces()
+			if dups := p.Duplicates(); len(dups) > 0 {
+				g.adjust(dups)
+				continue
+			}
+			if g.unique(p, solution) {
+				return p, solution, nil
+			}
+			g.adjust(nil)
+		}
+	}
+	return nil, board.Board{}, fmt.Errorf("no puzzle with a unique solution "+
+		"found in %d partitions", partitions)
+}
+
+// generator holds the pieces the board is cut into, as the ID of the piece
+// on each cell, counting from 1.  The limits are the number of different
+// shapes of each size, which is the most pieces of that size a puzzle
+// with no two pieces the same can have.
+type generator struct {
+	opts   Options
+	rng    *rand.Rand
+	owner  [][]int
+	sizes  []int
+	limits map[int]int
+}
+
+// newGenerator sets the limits on the number of pieces of each size, and
+// returns an error if pieces within those limits cannot fill the board.
+// Sizes beyond shape.MaxPolyomino have too many shapes to list, and more
+// than any board needs.
+func newGenerator(opts Options) (*generator, error) {
+	g := &generator{opts: opts, rng: rand.New(rand.NewSource(opts.Seed)),
+		limits: map[int]int{}}
+	area := 0
+	for n := opts.MinSize; n <= opts.MaxSize; n++ {
+		g.limits[n] = opts.Rows * opts.Cols
+		if free, err := shape.Polyominoes(n, shape.Free); err == nil {
+			g.limits[n] = len(free)
+		}
+		area += n * g.limits[n]
+	}
+	if area < opts.Rows*opts.Cols {
+		return nil, fmt.Errorf("different pieces of %d to %d cells cover "+
+			"at most %d cells, less than the %dx%d board", opts.MinSize,
+			opts.MaxSize, area, opts.Rows, opts.Cols)
+	}
+	return g, nil
+}
+
+// neighbors returns the cells next to the cell at r, c on the board.
+func (g *generator) neighbors(r int, c int) [][2]int {
+	cells := [][2]int{}
+	for _, d := range [][2]int{{-1, 0}, {1, 0}, {0, -1}, {0, 1}} {
+		nr, nc := r+d[0], c+d[1]
+		if nr >= 0 && nr < g.opts.Rows && nc >= 0 && nc < g.opts.Cols {
+			cells = append(cells, [2]int{nr, nc})
+		}
+	}
+	return cells
+}
+
+// partition cuts the board into pieces, each grown from the first cell
+// not yet taken by adding neighboring cells at random until it reaches a
+// random size, chosen from the sizes which have fewer pieces than their
+// limit.  A piece which is boxed in below the minimum size joins a
+// neighbor if that keeps within the maximum size.  It returns false if a
+// piece could not be made big enough.
+func (g *generator) partition() bool {
+
+	opts := g.opts
+	g.owner = make([][]int, opts.Rows)
+	for r := range g.owner {
+		g.owner[r] = make([]int, opts.Cols)
+	}
+	g.sizes = []int{0}
+	for r := 0; r < opts.Rows; r++ {
+		for c := 0; c < opts.Cols; c++ {
+			if g.owner[r][c] != 0 {
+				continue
+			}
+			id := len(g.sizes)
+			target := g.size()
+			cells := [][2]int{{r, c}}
+			g.owner[r][c] = id
+			for len(cells) < target {
+				frontier := [][2]int{}
+				for _, cell := range cells {
+					for _, n := range g.neighbors(cell[0], cell[1]) {
+						if g.owner[n[0]][n[1]] == 0 {
+							frontier = append(frontier, n)
+						}
+					}
+				}
+				if len(frontier) == 0 {
+					break
+				}
+				n := frontier[g.rng.Intn(len(frontier))]
+				g.owner[n[0]][n[1]] = id
+				cells = append(cells, n)
+			}
+			g.sizes = append(g.sizes, len(cells))
+			if len(cells) < opts.MinSize && !g.merge(id, cells) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// size returns a random size for a new piece, from the sizes with fewer
+// pieces than their limit, or from every size if all are at the limit.
+func (g *generator) size() int {
+	count := map[int]int{}
+	for _, n := range g.sizes {
+		count[n]++
+	}
+	sizes := []int{}
+	for n := g.opts.MinSize; n <= g.opts.MaxSize; n++ {
+		if count[n] < g.limits[n] {
+			sizes = append(sizes, n)
+		}
+	}
+	if len(sizes) == 0 {
+		return g.opts.MinSize + g.rng.Intn(g.opts.MaxSize-g.opts.MinSize+1)
+	}
+	return sizes[g.rng.Intn(len(sizes))]
+}
+
+// merge joins the cells of the last piece made onto a neighboring piece
+// which stays within the maximum size, and returns false if there is none.
+func (g *generator) merge(id int, cells [][2]int) bool {
+	for _, cell := range cells {
+		for _, n := range g.neighbors(cell[0], cell[1]) {
+			other := g.owner[n[0]][n[1]]
+			if other == id || other == 0 ||
+				g.sizes[other]+len(cells) > g.opts.MaxSize {
+				continue
+			}
+			for _, cell := range cells {
+				g.owner[cell[0]][cell[1]] = other
+			}
+			g.sizes[other] += len(cells)
+			g.sizes = g.sizes[:id]
+			return true
+		}
+	}
+	return false
+}
+
+// mask returns the mask of the cells of piece id.
+func (g *generator) mask(id int) mask.Bits {
+	m := mask.Bits(0)
+	for r, row := range g.owner {
+		for c, owner := range row {
+			if owner == id {
+				m |= mask.Cell(r, c)
+			}
+		}
+	}
+	return m
+}
+
+// connected returns true if every cell of m can be reached from every
+// other through the cells of m.
+func connected(m mask.Bits) bool {
+	if m == 0 {
+		return false
+	}
+	region := m & -m
+	for {
+		next := region | region.Translate(0, 1) | region.Translate(0, -1) |
+			region.Translate(1, 0) | region.Translate(-1, 0)
+		next &= m
+		if next == region {
+			return region == m
+		}
+		region = next
+	}
+}
+
+// adjust moves a random cell on the edge of a piece to the neighboring
+// piece, if both pieces keep within the sizes and the first stays
+// connected.  When some pieces are the same shape, the move must not leave
+// more pieces over the limits of their sizes or more pieces the same
+// shape, and moves which reshape one of the duplicates are tried first.
+func (g *generator) adjust(dups [][]shape.Shape) {
+
+	opts := g.opts
+	reshape := map[int]bool{}
+	for _, group := range dups {
+		for _, s := range group {
+			reshape[s.ID()] = true
+		}
+	}
+	moves := [][4]int{}
+	for r := 0; r < opts.Rows; r++ {
+		for c := 0; c < opts.Cols; c++ {
+			for _, n := range g.neighbors(r, c) {
+				if g.owner[r][c] != g.owner[n[0]][n[1]] {
+					moves = append(moves, [4]int{r, c, n[0], n[1]})
+				}
+			}
+		}
+	}
+	g.rng.Shuffle(len(moves), func(i, j int) {
+		moves[i], moves[j] = moves[j], moves[i]
+	})
+	sort.SliceStable(moves, func(i, j int) bool {
+		a, b := moves[i], moves[j]
+		return (reshape[g.owner[a[0]][a[1]]] || reshape[g.owner[a[2]][a[3]]]) &&
+			!(reshape[g.owner[b[0]][b[1]]] || reshape[g.owner[b[2]][b[3]]])
+	})
+
+	excess, same := g.excess(), g.duplicates()
+	for _, mv := range moves {
+		r, c := mv[0], mv[1]
+		from, to := g.owner[r][c], g.owner[mv[2]][mv[3]]
+		if g.sizes[from] <= opts.MinSize || g.sizes[to] >= opts.MaxSize ||
+			!connected(g.mask(from)&^mask.Cell(r, c)) {
+			continue
+		}
+		g.owner[r][c] = to
+		g.sizes[from]--
+		g.sizes[to]++
+		if len(reshape) > 0 &&
+			(g.excess() > excess || g.duplicates() > same) {
+			g.owner[r][c] = from
+			g.sizes[from]++
+			g.sizes[to]--
+			continue
+		}
+		return
+	}
+}
+
+// duplicates returns the number of pieces which are the same shape as an
+// earlier piece.
+func (g *generator) duplicates() int {
+	seen := map[shape.Key]bool{}
+	n := 0
+	for id := 1; id < len(g.sizes); id++ {
+		key := shape.FromMask(id, g.mask(id).Normalize()).CanonicalKey(
+			shape.Free)
+		if seen[key] {
+			n++
+		}
+		seen[key] = true
+	}
+	return n
+}
+
+// excess returns the number of pieces beyond the limits of their sizes,
+// each of which must be the same shape as another piece.
+func (g *generator) excess() int {
+	count := map[int]int{}
+	for _, n := range g.sizes[1:] {
+		count[n]++
+	}
+	excess := 0
+	for n, k := range count {
+		excess += max(k-g.limits[n], 0)
+	}
+	return excess
+}
+
+// pieces returns the puzzle made of the current pieces, and the board they
+// were cut from as its solution.
+func (g *generator) pieces() (*puzzle.Puzzle, board.Board) {
+	opts := g.opts
+	p := &puzzle.Puzzle{Name: fmt.Sprintf("generated %dx%d seed %d",
+		opts.Rows, opts.Cols, opts.Seed),
+		Board: board.NewBoard(opts.Rows, opts.Cols)}
+	solution := p.Board
+	for id := 1; id < len(g.sizes); id++ {
+		m := g.mask(id)
+		name := strconv.Itoa(id)
+		if id <= len(pieceNames) {
+			name = pieceNames[id-1 : id]
+		}
+		p.Shapes = append(p.Shapes,
+			shape.FromMask(id, m.Normalize()).WithName(name))
+		solution = solution.Place(shape.FromMask(id, m))
+	}
+	return p, solution
+}
+
+// unique reports whether every solution of the puzzle is the cut it was
+// made from, turned or flipped over with the board.  Those are at most
+// the images of the cut under the symmetries of the board, so the search
+// stops as soon as it finds one more solution than that, which cannot
+// all be images of the cut.
+func (g *generator) unique(p *puzzle.Puzzle, solution board.Board) bool {
+
+	grid := solution.Grid()
+	want, n := tilingKey(grid), len(images(grid))
+	opts := board.Options{Strategy: board.ConstrainedCell,
+		MaxSolutions: int64(n) + 1, Logger: g.opts.Logger}
+	solutions, err := p.Board.SolveOptions(p.Shapes, opts)
+	if err != nil {
+		return false
+	}
+	ok := true
+	for b := range solutions {
+		if n--; tilingKey(b.Grid()) != want {
+			ok = false
+		}
+	}
+	return ok && n >= 0
+}
+
+// tilingKey identifies a grid of piece IDs up to the symmetries of the
+// board: the smallest of its images.
+func tilingKey(grid [][]int) string {
+	key := ""
+	for k := range images(grid) {
+		if key == "" || k < key {
+			key = k
+		}
+	}
+	return key
+}
+
+// images returns the distinct images of a grid of piece IDs under the
+// reflections of the rows and columns, and under transposition as well if
+// the board is square, each formatted as a string.
+func images(grid [][]int) map[string]bool {
+	grids := [][][]int{grid, reverseRows(grid)}
+	grids = append(grids, reverseCols(grids[0]), reverseCols(grids[1]))
+	if len(grid) == len(grid[0]) {
+		for _, g := range grids[:4] {
+			grids = append(grids, transpose(g))
+		}
+	}
+	keys := map[string]bool{}
+	for _, g := range grids {
+		keys[fmt.Sprint(g)] = true
+	}
+	return keys
+}
+
+func reverseRows(grid [][]int) [][]int {
+	out := make([][]int, len(grid))
+	for r, row := range grid {
+		out[len(grid)-1-r] = row
+	}
+	return out
+}
+
+func reverseCols(grid [][]int) [][]int {
+	out := make([][]int, len(grid))
+	for r, row := range grid {
+		out[r] = make([]int, len(row))
+		for c, id := range row {
+			out[r][len(row)-1-c] = id
+		}
+	}
+	return out
+}
+
+func transpose(grid [][]int) [][]int {
+	out := make([][]int, len(grid[0]))
+	for c := range out {
+		out[c] = make([]int, len(grid))
+		for r, row := range grid {
+			out[c][r] = row[c]
+		}
+	}
+	return out
+}
@@ -0,0 +1,117 @@
+// -*- tab-width: 4; -*-
+
+package generate
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/garyjg/shapepuzzle/board"
+	"github.com/garyjg/shapepuzzle/puzzle"
+)
+
+func TestGenerate(t *testing.T) {
+
+	opts := Options{Rows: 5, Cols: 5, MinSize: 4, MaxSize: 6, Seed: 1,
+		Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	p, solution, err := Generate(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if solution.Mask() != solution.RegionMask() {
+		t.Errorf("Solution does not fill the board:\n%v", solution)
+	}
+	for _, s := range p.Shapes {
+		if n := s.Mask().Count(); n < opts.MinSize || n > opts.MaxSize {
+			t.Errorf("Piece %s has %d cells", s.Name(), n)
+		}
+	}
+
+	// The puzzle reads back, and every solution of it is the one the board
+	// was cut into, turned or flipped.
+	var buf bytes.Buffer
+	if err := p.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	again, err := puzzle.Parse(&buf)
+	if err != nil {
+		t.Fatalf("%v:\n%s", err, buf.String())
+	}
+	want := tilingKey(solution.Grid())
+	solutions, err := again.Board.SolveOptions(again.Shapes,
+		board.Options{Strategy: board.FirstCell, Logger: opts.Logger})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	for b := range solutions {
+		if tilingKey(b.Grid()) != want {
+			t.Errorf("Second solution:\n%v", b)
+		}
+		n++
+	}
+	// A square board has eight symmetries.
+	if n != 8 {
+		t.Errorf("Found %d solutions, want 8", n)
+	}
+}
+
+func TestTilingKey(t *testing.T) {
+	grid := [][]int{{1, 1, 2}, {3, 2, 2}}
+	turned := [][]int{{2, 2, 3}, {2, 1, 1}}
+	other := [][]int{{1, 2, 2}, {1, 3, 2}}
+	if tilingKey(grid) != tilingKey(turned) {
+		t.Errorf("Turned grid has a different key")
+	}
+	if tilingKey(grid) == tilingKey(other) {
+		t.Errorf("Different grids have the same key")
+	}
+	// The unique search stops after one solution more than the images.
+	if n := len(images(grid)); n != 4 {
+		t.Errorf("Grid has %d images, want 4", n)
+	}
+	if n := len(images([][]int{{1, 1}, {1, 1}})); n != 1 {
+		t.Errorf("Symmetric grid has %d images, want 1", n)
+	}
+}
+
+func TestDistinctPieces(t *testing.T) {
+
+	// An 8x8 board cut into pieces of three to five cells has too few
+	// shapes for the pieces to differ at random: there are only two
+	// trominoes.  Cuts with too many pieces of a size are thrown away, and
+	// the pieces of the others are reshaped until they differ.
+	opts := Options{Rows: 8, Cols: 8, MinSize: 3, MaxSize: 5, Seed: 1}
+	g, err := newGenerator(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		if !g.partition() || g.excess() > 0 {
+			continue
+		}
+		for j := 0; j < 1000; j++ {
+			p, _ := g.pieces()
+			dups := p.Duplicates()
+			if len(dups) == 0 {
+				return
+			}
+			g.adjust(dups)
+		}
+	}
+	t.Errorf("No cut into different pieces found")
+}
+
+func TestGenerateErrors(t *testing.T) {
+	for _, opts := range []Options{
+		{Rows: 9, Cols: 5, MinSize: 4, MaxSize: 6},
+		{Rows: 5, Cols: 5, MinSize: 6, MaxSize: 4},
+		{Rows: 2, Cols: 2, MinSize: 1, MaxSize: 5},
+		{Rows: 8, Cols: 8, MinSize: 1, MaxSize: 3}} {
+		if _, _, err := Generate(opts); err == nil {
+			t.Errorf("Generate(%+v) should fail", opts)
+		}
+	}
+}
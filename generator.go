@@ -0,0 +1,78 @@
+// -*- tab-width: 4; -*-
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/garyjg/shapepuzzle/generate"
+)
+
+// generatePuzzle designs a puzzle with a unique solution and writes it in
+// the puzzle file format, followed by its solution as comments.
+func generatePuzzle(args []string) int {
+
+	fs := flag.NewFlagSet("generate", flag.ContinueOnError)
+	rows := fs.Int("rows", 6, "number of rows on the board")
+	cols := fs.Int("cols", 6, "number of columns on the board")
+	minSize := fs.Int("min", 4, "fewest cells in a piece")
+	maxSize := fs.Int("max", 6, "most cells in a piece")
+	seed := fs.Int64("seed", 0,
+		"seed for the random pieces, 0 to choose one from the clock")
+	output := fs.String("o", "", "write the puzzle to this file")
+	logLevel := fs.String("log-level", "warn",
+		"log messages at this level or above: debug, info, warn or error")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: shapepuzzle generate [flags]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return 2
+	}
+	logger, err := newLogger(*logLevel)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+
+	p, solution, err := generate.Generate(generate.Options{Rows: *rows,
+		Cols: *cols, MinSize: *minSize, MaxSize: *maxSize, Seed: *seed,
+		Logger: logger})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := p.Write(w); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	// Comments in a puzzle file start with '#' and a space.
+	fmt.Fprintf(w, "# Unique solution:\n")
+	for _, line := range strings.Split(strings.TrimSpace(solution.String()),
+		"\n") {
+		fmt.Fprintf(w, "# %s\n", line)
+	}
+	return 0
+}
@@ -0,0 +1,213 @@
+// -*- tab-width: 4; -*-
+
+package hex
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/garyjg/shapepuzzle/cover"
+	"github.com/garyjg/shapepuzzle/mask"
+)
+
+// Placement is a shape at one position on a board, given by the shape's ID
+// and the mask of the cells it covers.
+type Placement = cover.Placement[mask.Bits]
+
+type filling = cover.Filling[mask.Bits]
+
+// Board is a region of hexagonal cells with the shape placements made on
+// it so far.
+type Board struct {
+	filling
+	region mask.Bits
+}
+
+// NewBoard makes an empty board of the cells in region.
+func NewBoard(region mask.Bits) Board {
+	return Board{region: region}
+}
+
+// NewHexagon makes a board in the shape of a hexagon with side cells on
+// each side.  A side of 4 cells, 37 in all, is the largest which fits in a
+// mask.
+func NewHexagon(side int) (Board, error) {
+	if side < 1 || side > 4 {
+		return Board{}, fmt.Errorf("hexagon side is %d, but must be from "+
+			"1 to 4", side)
+	}
+	n := side - 1
+	region := mask.Bits(0)
+	for r := 0; r <= 2*n; r++ {
+		for q := 0; q <= 2*n; q++ {
+			if q+r >= n && q+r <= 3*n {
+				region |= Point{q, r}.Bit()
+			}
+		}
+	}
+	return NewBoard(region), nil
+}
+
+// NewParallelogram makes a board of rows of cols cells, each row half a
+// cell to the right of the one above.
+func NewParallelogram(rows int, cols int) (Board, error) {
+	if rows < 1 || rows > 8 || cols < 1 || cols > 8 {
+		return Board{}, fmt.Errorf("parallelogram is %dx%d, but must be "+
+			"from 1x1 to 8x8", rows, cols)
+	}
+	region := mask.Bits(0)
+	for r := 0; r < rows; r++ {
+		for q := 0; q < cols; q++ {
+			region |= Point{q, r}.Bit()
+		}
+	}
+	return NewBoard(region), nil
+}
+
+// Region returns the mask of every cell of the board.
+func (b Board) Region() mask.Bits {
+	return b.region
+}
+
+// Place returns a copy of the Board with the placement added.  It does
+// not check that the placement fits.
+func (b Board) Place(p Placement) Board {
+	b.filling = b.filling.Place(p)
+	return b
+}
+
+// placementsOf returns every orientation of the Shape translated to every
+// position on the Board which does not overlap the cells already filled.
+func (b Board) placementsOf(s Shape, mirror bool) []mask.Bits {
+	places := []mask.Bits{}
+	free := b.region &^ b.Mask()
+	for _, o := range s.Orientations(mirror) {
+		n := o.mask.Count()
+		for r := 0; r < 8; r++ {
+			for q := 0; q < 8; q++ {
+				m := o.mask.Translate(r, q)
+				if m.Count() == n && m&^free == 0 {
+					places = append(places, m)
+				}
+			}
+		}
+	}
+	return places
+}
+
+// Channel is a channel for passing Board states.
+type Channel chan Board
+
+// Solve searches for every way to fill the rest of the board with one
+// placement of each shape, and sends each solution on the channel it
+// returns, which is closed when the search is done.  Shapes are turned by
+// the six rotations of the hexagon, and flipped over as well if mirror is
+// true.  It returns an error, without searching, if there are more shapes
+// than the search can place.
+func (b Board) Solve(shapes []Shape, mirror bool) (Channel, error) {
+
+	p := &cover.Problem{Region: cover.FromMask(b.region &^ b.Mask()),
+		Neighbors: func(s cover.Set) cover.Set {
+			return cover.FromMask(Neighbors(s.Mask()) & b.region)
+		}}
+	for _, s := range shapes {
+		places := []cover.Set{}
+		for _, m := range b.placementsOf(s, mirror) {
+			places = append(places, cover.FromMask(m))
+		}
+		p.Pieces = append(p.Pieces, places)
+	}
+	bc, err := cover.Stream(p, func(choices []cover.Choice) Board {
+		nb := b
+		for _, ch := range choices {
+			nb = nb.Place(Placement{ID: shapes[ch.Piece].ID(),
+				Mask: ch.Cells.Mask()})
+		}
+		return nb
+	})
+	return bc, err
+}
+
+// id returns the ID of the shape placed on the cell, or 0.
+func (b Board) id(p Point) int {
+	return b.ID(p.Bit())
+}
+
+// String draws the Board with the ID of the shape in each cell, each row
+// shifted half a cell to the right of the one above so that every cell
+// sits between its neighbors in the rows above and below.
+func (b Board) String() string {
+	left := math.MaxInt
+	for r, q := range b.region.Cells() {
+		left = min(left, 2*q+r)
+	}
+	buf := ""
+	for row := 0; row < 8; row++ {
+		line := ""
+		for r, q := range b.region.Cells() {
+			if r != row {
+				continue
+			}
+			// Each cell is four characters wide, so half a cell is two.
+			if pad := 2*(2*q+r-left) - len(line); pad > 0 {
+				line += strings.Repeat(" ", pad)
+			}
+			line += fmt.Sprintf(" %2d ", b.id(Point{q, r}))
+		}
+		if line != "" {
+			buf += strings.TrimRight(line, " ") + "\n"
+		}
+	}
+	return buf
+}
+
+// svgSize is the distance in pixels from the centre of a hexagon to each
+// of its corners.
+const svgSize = 20
+
+// WriteSVG draws the Board as an SVG image, with pointed-top hexagons
+// colored by the shape placed on them and labelled with its ID.  Empty
+// cells are white.
+func (b Board) WriteSVG(w io.Writer) error {
+
+	width := math.Sqrt(3) * svgSize
+	center := func(p Point) (float64, float64) {
+		return width * (float64(p.Q) + float64(p.R)/2 + 1),
+			1.5*svgSize*float64(p.R) + svgSize*1.5
+	}
+	maxx, maxy := 0.0, 0.0
+	for r, q := range b.region.Cells() {
+		x, y := center(Point{q, r})
+		maxx, maxy = max(maxx, x), max(maxy, y)
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "<svg xmlns=\"http://www.w3.org/2000/svg\" "+
+		"width=\"%.0f\" height=\"%.0f\">\n", maxx+width, maxy+1.5*svgSize)
+	for r, q := range b.region.Cells() {
+		x, y := center(Point{q, r})
+		corners := []string{}
+		for i := 0; i < 6; i++ {
+			angle := math.Pi / 180 * float64(60*i-30)
+			corners = append(corners, fmt.Sprintf("%.1f,%.1f",
+				x+svgSize*math.Cos(angle), y+svgSize*math.Sin(angle)))
+		}
+		id := b.id(Point{q, r})
+		fill := "white"
+		if id != 0 {
+			fill = fmt.Sprintf("hsl(%d,70%%,70%%)", id*137%360)
+		}
+		fmt.Fprintf(bw, "<polygon points=\"%s\" fill=\"%s\" "+
+			"stroke=\"black\"/>\n", strings.Join(corners, " "), fill)
+		if id != 0 {
+			fmt.Fprintf(bw, "<text x=\"%.1f\" y=\"%.1f\" "+
+				"text-anchor=\"middle\" dominant-baseline=\"central\" "+
+				"font-size=\"12\">%d</text>\n", x, y, id)
+		}
+	}
+	fmt.Fprintf(bw, "</svg>\n")
+	return bw.Flush()
+}
@@ -0,0 +1,163 @@
+// -*- tab-width: 4; -*-
+
+package hex
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/garyjg/shapepuzzle/shape"
+)
+
+func TestPolyhexes(t *testing.T) {
+	// The numbers of free, one-sided and fixed polyhexes of 1 to 8 cells.
+	expect := map[shape.Kind][]int{
+		shape.Free:     {1, 1, 3, 7, 22, 82, 333, 1448},
+		shape.OneSided: {1, 1, 3, 10, 33, 147, 620, 2821},
+		shape.Fixed:    {1, 3, 11, 44, 186, 814, 3652, 16689},
+	}
+	for kind, counts := range expect {
+		for n := 1; n <= 6 || n <= MaxPolyhex && !testing.Short(); n++ {
+			shapes, err := Polyhexes(n, kind)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(shapes) != counts[n-1] {
+				t.Errorf("got %d %v polyhexes of %d cells, expected %d",
+					len(shapes), kind, n, counts[n-1])
+			}
+		}
+	}
+	if _, err := Polyhexes(MaxPolyhex+1, shape.Free); err == nil {
+		t.Errorf("polyhexes of %d cells should be an error", MaxPolyhex+1)
+	}
+}
+
+func TestTransform(t *testing.T) {
+	s, err := NewShape(1, [][]int{{1, 1, 1}, {1, 0, 0}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	turned := s
+	for i := 0; i < 6; i++ {
+		if i > 0 && turned.Mask() == s.Mask() {
+			t.Errorf("chiral shape is unchanged by %d turns", i)
+		}
+		turned = turned.Transform(1)
+	}
+	if turned.Mask() != s.Mask() {
+		t.Errorf("six turns changed %v to %v", s, turned)
+	}
+	if n := len(s.Orientations(false)); n != 6 {
+		t.Errorf("got %d rotations, expected 6", n)
+	}
+	if n := len(s.Orientations(true)); n != 12 {
+		t.Errorf("got %d orientations, expected 12", n)
+	}
+}
+
+func TestNeighbors(t *testing.T) {
+	tests := []struct {
+		p     Point
+		count int
+	}{
+		{Point{0, 0}, 2},
+		{Point{7, 0}, 3},
+		{Point{3, 3}, 6},
+		{Point{0, 7}, 3},
+		{Point{7, 7}, 2},
+	}
+	for _, test := range tests {
+		if got := Neighbors(test.p.Bit()).Count(); got != test.count {
+			t.Errorf("%v has %d neighbors, expected %d", test.p, got,
+				test.count)
+		}
+	}
+}
+
+func TestNewShape(t *testing.T) {
+	if _, err := NewShape(1, [][]int{{1, 0}, {0, 1}}); err == nil {
+		t.Errorf("cells touching at a corner should not be connected")
+	}
+	if _, err := NewShape(1, [][]int{{0}}); err == nil {
+		t.Errorf("shape with no cells should be an error")
+	}
+	// A parallelogram of 8x2 cells fits in a mask, but not once turned.
+	grid := [][]int{{1, 1, 1, 1, 1, 1, 1, 1}, {1, 1, 1, 1, 1, 1, 1, 1}}
+	if _, err := NewShape(1, grid); err == nil {
+		t.Errorf("shape too big to turn should be an error")
+	}
+}
+
+func countSolutions(t *testing.T, definition string) int {
+	p, err := Parse(strings.NewReader(definition))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bc, err := p.Board.Solve(p.Shapes, !p.OneSided)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	for b := range bc {
+		if b.Mask() != b.Region() {
+			t.Errorf("solution does not fill the board:\n%v", b)
+		}
+		n++
+	}
+	return n
+}
+
+func TestSolve(t *testing.T) {
+	if n := countSolutions(t, Tetrahexes); n != 18 {
+		t.Errorf("tetrahexes have %d solutions, expected 18", n)
+	}
+	// Two dominoes fill a rhombus of four cells side by side in either
+	// direction, and in either order.
+	rhombus := "board parallelogram 2 2\npiece A\n##\npiece B\n##\n"
+	if n := countSolutions(t, rhombus); n != 4 {
+		t.Errorf("dominoes have %d solutions, expected 4", n)
+	}
+}
+
+func TestString(t *testing.T) {
+	b, err := NewHexagon(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b = b.Place(Placement{ID: 5, Mask: Point{1, 1}.Bit() | Point{2, 1}.Bit()})
+	expect := "    0   0\n" +
+		"  0   5   5\n" +
+		"    0   0\n"
+	if got := b.String(); got != expect {
+		t.Errorf("got board\n%s, expected\n%s", got, expect)
+	}
+	var buf bytes.Buffer
+	if err := b.WriteSVG(&buf); err != nil {
+		t.Fatal(err)
+	}
+	svg := buf.String()
+	if n := strings.Count(svg, "<polygon"); n != 7 {
+		t.Errorf("svg has %d hexagons, expected 7", n)
+	}
+	if n := strings.Count(svg, "<text"); n != 2 {
+		t.Errorf("svg has %d labels, expected 2", n)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	// Errors in the directives every grid shares are tested in the puzzle
+	// package.
+	tests := []string{
+		"board hexagon 5\npiece A\n#\n",
+		"board square 2\npiece A\n#\n",
+		"board parallelogram 2\npiece A\n#\n",
+		"board hexagon 2\npiece A\n#.\n.#\n",
+	}
+	for _, test := range tests {
+		if _, err := Parse(strings.NewReader(test)); err == nil {
+			t.Errorf("parsing %q should fail", test)
+		}
+	}
+}
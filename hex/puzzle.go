@@ -0,0 +1,122 @@
+// -*- tab-width: 4; -*-
+
+package hex
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/garyjg/shapepuzzle/puzzle"
+)
+
+// Puzzle is a board and the polyhexes which must fill it.  Pieces may be
+// flipped over unless OneSided is true.
+//
+// A hex puzzle file has the directives of the puzzle package, with the
+// board given as "board hexagon SIDE" or "board parallelogram ROWS COLS",
+// and pieces drawn in axial coordinates: each row of the grid is half a
+// cell to the right of the row above, although it is written below it.
+//
+//	# Two of the seven tetrahexes.
+//	name example
+//	board parallelogram 4 7
+//	piece bar
+//	####
+//	piece bee
+//	##
+//	##
+//
+// The bee is a rhombus: its second row is shifted half a cell to the
+// right.  A one-sided directive stops pieces from being flipped over.
+type Puzzle struct {
+	Name     string
+	Board    Board
+	Shapes   []Shape
+	OneSided bool
+}
+
+// Load reads the hex puzzle definition in the file at path.
+func Load(path string) (*Puzzle, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	p, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return p, nil
+}
+
+// grammar describes hex puzzle files to puzzle.ParseDefinition.
+var grammar = puzzle.Grammar[Board, Shape]{
+	Flags: []string{"one-sided"},
+	Board: parseBoard,
+	Piece: func(id int, grid []string) (Shape, error) {
+		return NewShape(id, puzzle.Cells(grid))
+	},
+	Name: Shape.WithName,
+}
+
+// Parse reads a hex puzzle definition.  Errors give the line number of
+// the directive at fault.
+func Parse(r io.Reader) (*Puzzle, error) {
+	d, err := puzzle.ParseDefinition(r, grammar)
+	if err != nil {
+		return nil, err
+	}
+	return &Puzzle{Name: d.Name, Board: d.Board, Shapes: d.Shapes,
+		OneSided: d.Flags["one-sided"]}, nil
+}
+
+func parseBoard(args []string) (Board, error) {
+	sizes := []int{}
+	for _, arg := range args[min(1, len(args)):] {
+		n, err := strconv.Atoi(arg)
+		if err != nil {
+			return Board{}, fmt.Errorf("bad board size: %v", err)
+		}
+		sizes = append(sizes, n)
+	}
+	switch {
+	case len(args) == 2 && args[0] == "hexagon":
+		return NewHexagon(sizes[0])
+	case len(args) == 3 && args[0] == "parallelogram":
+		return NewParallelogram(sizes[0], sizes[1])
+	}
+	return Board{}, fmt.Errorf("board needs hexagon SIDE or " +
+		"parallelogram ROWS COLS")
+}
+
+// Tetrahexes is the seven tetrahexes, under their usual names, which fill
+// a parallelogram of four rows of seven cells in 18 ways, counting each
+// solution and its half turn as two.
+const Tetrahexes = `
+name tetrahexes
+board parallelogram 4 7
+piece bar
+####
+piece pistol
+###
+#..
+piece worm
+###
+..#
+piece bee
+##
+##
+piece wave
+##.
+.##
+piece arch
+##
+.#
+#.
+piece propeller
+.#.
+.##
+#..
+`
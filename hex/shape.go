@@ -0,0 +1,291 @@
+// -*- tab-width: 4; -*-
+
+// Package hex solves polyhex puzzles, on boards of hexagonal cells.
+//
+// Cells are given axial coordinates: Q counts cells along a row and R
+// counts rows, each row drawn half a cell to the right of the one above,
+// so that the six neighbors of a cell differ by one in Q, in R, or in both
+// with opposite signs.  The cell at (Q, R) is the mask cell at row R and
+// column Q, which makes mask.Bits.Translate a translation on the hex grid
+// as well, and any board which fits in 8 rows of 8 cells fits in a mask.
+package hex
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/garyjg/shapepuzzle/mask"
+	"github.com/garyjg/shapepuzzle/shape"
+)
+
+// Point is a cell in axial coordinates.
+type Point struct {
+	Q, R int
+}
+
+// Bit returns the mask with only the cell at p set.
+func (p Point) Bit() mask.Bits {
+	return mask.Cell(p.R, p.Q)
+}
+
+// points lists the cells of a mask.
+func points(m mask.Bits) []Point {
+	pts := []Point{}
+	for r, q := range m.Cells() {
+		pts = append(pts, Point{q, r})
+	}
+	return pts
+}
+
+// fromPoints moves the cells as close to the origin as they go and returns
+// their mask, or an error if they do not fit in a mask.
+func fromPoints(pts []Point) (mask.Bits, error) {
+	if len(pts) == 0 {
+		return 0, nil
+	}
+	lo := pts[0]
+	for _, p := range pts {
+		lo = Point{min(lo.Q, p.Q), min(lo.R, p.R)}
+	}
+	m := mask.Bits(0)
+	for _, p := range pts {
+		q, r := p.Q-lo.Q, p.R-lo.R
+		if q >= 8 || r >= 8 {
+			return 0, fmt.Errorf("polyhex spans more than 8 rows or columns")
+		}
+		m |= Point{q, r}.Bit()
+	}
+	return m, nil
+}
+
+// Neighbors returns the cells which share an edge with any cell of m and
+// are not in m themselves.
+func Neighbors(m mask.Bits) mask.Bits {
+	n := m.Translate(0, 1) | m.Translate(0, -1) | m.Translate(1, 0) |
+		m.Translate(-1, 0) | m.Translate(1, -1) | m.Translate(-1, 1)
+	return n &^ m
+}
+
+// connected returns true if every cell of m can be reached from every
+// other through cells of m.
+func connected(m mask.Bits) bool {
+	if m == 0 {
+		return true
+	}
+	region := m & -m
+	for {
+		next := region | Neighbors(region)&m
+		if next == region {
+			return region == m
+		}
+		region = next
+	}
+}
+
+// Shape is a polyhex: a connected set of cells, moved to the origin, with
+// an ID and an optional name.
+type Shape struct {
+	id   int
+	name string
+	mask mask.Bits
+}
+
+// NewShape makes a Shape from rows of cells in axial coordinates, where
+// cells which are not 0 are part of the shape.  It returns an error if the
+// shape is empty, not connected, or too big for a mask in any of its
+// orientations.
+func NewShape(id int, grid [][]int) (Shape, error) {
+	pts := []Point{}
+	for r, row := range grid {
+		for q, v := range row {
+			if v != 0 {
+				pts = append(pts, Point{q, r})
+			}
+		}
+	}
+	m, err := fromPoints(pts)
+	if err == nil && m == 0 {
+		err = fmt.Errorf("no cells")
+	}
+	if err == nil && !connected(m) {
+		err = fmt.Errorf("not connected")
+	}
+	for t := Transform(0); err == nil && t < 12; t++ {
+		_, err = transform(m, t)
+	}
+	if err != nil {
+		return Shape{}, fmt.Errorf("polyhex #%d: %v", id, err)
+	}
+	return Shape{id: id, mask: m}, nil
+}
+
+// WithName returns a copy of the Shape with the given name.
+func (s Shape) WithName(name string) Shape {
+	s.name = name
+	return s
+}
+
+// ID returns the integer id of the Shape.
+func (s Shape) ID() int {
+	return s.id
+}
+
+// Name returns the name of the Shape, if it has one.
+func (s Shape) Name() string {
+	return s.name
+}
+
+// Mask returns the mask of the Shape at the origin.
+func (s Shape) Mask() mask.Bits {
+	return s.mask
+}
+
+func (s Shape) String() string {
+	return fmt.Sprintf("Polyhex #%d %s %v", s.id, s.name, points(s.mask))
+}
+
+// Transform is one of the twelve symmetries of the hexagon: a rotation by
+// a multiple of 60 degrees, numbered 0 to 5, or a reflection followed by
+// one of those rotations, numbered 6 to 11.
+type Transform int
+
+// Apply returns the cell p after the transform t, about the origin.
+func (t Transform) Apply(p Point) Point {
+	if t >= 6 {
+		p = Point{p.R, p.Q}
+	}
+	for i := 0; i < int(t)%6; i++ {
+		// A sixth of a turn clockwise.
+		p = Point{-p.R, p.Q + p.R}
+	}
+	return p
+}
+
+// Transform returns the Shape after the transform t, moved back to the
+// origin.
+func (s Shape) Transform(t Transform) Shape {
+	// NewShape and Polyhexes only make shapes which fit in a mask in
+	// every orientation.
+	s.mask, _ = transform(s.mask, t)
+	return s
+}
+
+func transform(m mask.Bits, t Transform) (mask.Bits, error) {
+	pts := points(m)
+	for i, p := range pts {
+		pts[i] = t.Apply(p)
+	}
+	return fromPoints(pts)
+}
+
+// Orientations returns the distinct orientations of the Shape under the
+// six rotations, and the six reflections as well if mirror is true.
+func (s Shape) Orientations(mirror bool) []Shape {
+	n := Transform(6)
+	if mirror {
+		n = 12
+	}
+	seen := map[mask.Bits]bool{}
+	shapes := []Shape{}
+	for t := Transform(0); t < n; t++ {
+		o := s.Transform(t)
+		if !seen[o.mask] {
+			seen[o.mask] = true
+			shapes = append(shapes, o)
+		}
+	}
+	return shapes
+}
+
+// Canonical returns the orientation of the Shape with the largest mask
+// among those the kind of transforms allows, so that every orientation of
+// a shape gives the same one.
+func (s Shape) Canonical(kind shape.Kind) Shape {
+	if kind == shape.Fixed {
+		return s
+	}
+	best := s
+	for _, o := range s.Orientations(kind == shape.Free) {
+		if o.mask > best.mask {
+			best = o
+		}
+	}
+	return best
+}
+
+// MaxPolyhex is the largest number of cells Polyhexes generates.
+const MaxPolyhex = 8
+
+// Polyhexes generates every polyhex of n cells, from 1 up to MaxPolyhex,
+// with the given kind of transforms treated as the same shape, in the
+// order of their canonical masks.  One-sided shapes follow the free shape
+// they come from, and fixed shapes list every orientation of each free
+// shape.  IDs count from 1 in the order returned.
+func Polyhexes(n int, kind shape.Kind) ([]Shape, error) {
+
+	if n < 1 || n > MaxPolyhex {
+		return nil, fmt.Errorf("polyhexes need 1 to %d cells, not %d",
+			MaxPolyhex, n)
+	}
+	if kind != shape.Free && kind != shape.OneSided && kind != shape.Fixed {
+		return nil, fmt.Errorf("unknown polyhex kind: %v", kind)
+	}
+
+	// Every fixed polyhex of n cells is one of n-1 cells with a
+	// neighboring cell added.  The cells are grown as points, since a
+	// neighbor may be above or to the left of the origin.
+	fixed := []mask.Bits{mask.FirstBit()}
+	for size := 1; size < n; size++ {
+		seen := map[mask.Bits]bool{}
+		next := []mask.Bits{}
+		for _, m := range fixed {
+			pts := points(m)
+			for _, p := range pts {
+				for _, d := range []Point{{1, 0}, {-1, 0}, {0, 1}, {0, -1},
+					{1, -1}, {-1, 1}} {
+					grown, err := fromPoints(append(pts[:len(pts):len(pts)],
+						Point{p.Q + d.Q, p.R + d.R}))
+					if err == nil && grown.Count() == size+1 && !seen[grown] {
+						seen[grown] = true
+						next = append(next, grown)
+					}
+				}
+			}
+		}
+		fixed = next
+	}
+
+	free := map[mask.Bits]Shape{}
+	for _, m := range fixed {
+		s := Shape{mask: m}.Canonical(shape.Free)
+		free[s.mask] = s
+	}
+	keys := make([]mask.Bits, 0, len(free))
+	for key := range free {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] > keys[j] })
+
+	shapes := []Shape{}
+	for _, key := range keys {
+		s := free[key]
+		var add []Shape
+		switch kind {
+		case shape.Free:
+			add = []Shape{s}
+		case shape.OneSided:
+			add = []Shape{s}
+			mirror := s.Transform(6).Canonical(shape.OneSided)
+			if mirror.mask != s.Canonical(shape.OneSided).mask {
+				add = append(add, mirror)
+			}
+		case shape.Fixed:
+			add = s.Orientations(true)
+		}
+		for _, a := range add {
+			a.id = len(shapes) + 1
+			shapes = append(shapes, a)
+		}
+	}
+	return shapes, nil
+}
@@ -0,0 +1,70 @@
+// -*- tab-width: 4; -*-
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/garyjg/shapepuzzle/hex"
+)
+
+// solveHex solves the tetrahexes, or the hex puzzle defined in a file, and
+// prints every solution, writing the first to an SVG file if asked.
+func solveHex(args []string) int {
+
+	flags := flag.NewFlagSet("hex", flag.ContinueOnError)
+	svgFile := flags.String("svg", "", "write the first solution to this "+
+		"file as an SVG image")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: shapepuzzle hex [-svg FILE] "+
+			"tetrahexes|FILE")
+		return 2
+	}
+	var p *hex.Puzzle
+	var err error
+	if flags.Arg(0) == "tetrahexes" {
+		p, err = hex.Parse(strings.NewReader(hex.Tetrahexes))
+	} else {
+		p, err = hex.Load(flags.Arg(0))
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	bc, err := p.Board.Solve(p.Shapes, !p.OneSided)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	nfound := 0
+	for b := range bc {
+		fmt.Printf("Solution found.\n%s\n", b)
+		if nfound == 0 && *svgFile != "" {
+			if err := writeSVG(*svgFile, b); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 1
+			}
+		}
+		nfound++
+	}
+	printCount(nfound)
+	return 0
+}
+
+func writeSVG(path string, b hex.Board) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := b.WriteSVG(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
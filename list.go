@@ -0,0 +1,42 @@
+// -*- tab-width: 4; -*-
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/garyjg/shapepuzzle/catalog"
+)
+
+// list prints the puzzles in the catalog with their board sizes, numbers
+// of pieces and known numbers of solutions.
+func list() int {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "name\tboard\tpieces\tsolutions\tdescription\n")
+	for _, e := range catalog.All() {
+		var rows, cols, npieces int
+		if e.Rect {
+			p, err := e.RectPuzzle()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", e.Name, err)
+				return 1
+			}
+			rows, cols, npieces = p.Board.NumRows(), p.Board.NumCols(),
+				len(p.Shapes)
+		} else {
+			p, err := e.Puzzle()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", e.Name, err)
+				return 1
+			}
+			rows, cols, npieces = p.Board.NumRows(), p.Board.NumCols(),
+				len(p.Shapes)
+		}
+		fmt.Fprintf(tw, "%s\t%dx%d\t%d\t%d\t%s\n", e.Name, rows, cols,
+			npieces, e.Solutions, e.Description)
+	}
+	tw.Flush()
+	return 0
+}
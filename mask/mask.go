@@ -7,6 +7,10 @@ package mask
 
 import (
 	"fmt"
+	"iter"
+	"math/bits"
+	"strconv"
+	"strings"
 )
 
 // Bits type is an 8-byte unsigned integer to efficiently represent a
@@ -25,6 +29,27 @@ func (mask Bits) String() string {
 	return fmt.Sprintf("0x%016x", uint64(mask))
 }
 
+// MarshalText encodes the mask as the same hexadecimal text as String, so
+// masks are readable in JSON and other text formats.
+func (mask Bits) MarshalText() ([]byte, error) {
+	return []byte(mask.String()), nil
+}
+
+// UnmarshalText parses a mask in the format written by MarshalText.  The
+// 0x prefix is optional.
+func (mask *Bits) UnmarshalText(text []byte) error {
+	s := string(text)
+	if len(s) > 2 && (s[:2] == "0x" || s[:2] == "0X") {
+		s = s[2:]
+	}
+	u, err := strconv.ParseUint(s, 16, 64)
+	if err != nil {
+		return fmt.Errorf("invalid mask %q: %v", text, err)
+	}
+	*mask = Bits(u)
+	return nil
+}
+
 // ComputeMask turns a 2D array or slice into a mask on a nrow X ncol grid.
 // The most-significant bit in the mask is for the upper left corner of the
 // grid, r=0 and c=0, indexed in row major order.  Each row always starts
@@ -79,3 +104,132 @@ func (mask Bits) Translate(row int, col int) Bits {
 	}
 	return mask
 }
+
+// Cell returns a mask with only the bit for row r and column c set.
+func Cell(r int, c int) Bits {
+	return FirstBit() >> uint(r*8+c)
+}
+
+// Count returns the number of cells set in the mask.
+func (mask Bits) Count() int {
+	return bits.OnesCount64(uint64(mask))
+}
+
+// Cells iterates over the cells set in the mask as (row, column) pairs, in
+// row major order.
+func (mask Bits) Cells() iter.Seq2[int, int] {
+	return func(yield func(int, int) bool) {
+		for m := mask; m != 0; {
+			i := bits.LeadingZeros64(uint64(m))
+			m &^= FirstBit() >> uint(i)
+			if !yield(i/8, i%8) {
+				return
+			}
+		}
+	}
+}
+
+// BoundingBox returns the first row and column with any cells set, and
+// the number of rows and columns from there to the last cells set.  All
+// four are zero for an empty mask.
+func (mask Bits) BoundingBox() (row, col, nrows, ncols int) {
+	if mask == 0 {
+		return 0, 0, 0, 0
+	}
+	row = bits.LeadingZeros64(uint64(mask)) / 8
+	nrows = 8 - bits.TrailingZeros64(uint64(mask))/8 - row
+	// OR the rows together to find the columns used by any of them.
+	cols := uint8(0)
+	for m := uint64(mask); m != 0; m >>= 8 {
+		cols |= uint8(m)
+	}
+	col = bits.LeadingZeros8(cols)
+	ncols = 8 - bits.TrailingZeros8(cols) - col
+	return row, col, nrows, ncols
+}
+
+// Normalize moves the cells of the mask up and to the left until they
+// touch the first row and the first column.
+func (mask Bits) Normalize() Bits {
+	row, col, _, _ := mask.BoundingBox()
+	return mask.Translate(-row, -col)
+}
+
+// Transpose swaps the rows and columns of the full 8x8 grid, mirroring the
+// mask in the diagonal from the upper left corner.
+func (mask Bits) Transpose() Bits {
+	// Swap 4x4, then 2x2, then 1x1 blocks across the diagonal.
+	x := uint64(mask)
+	t := 0x0f0f0f0f00000000 & (x ^ x<<28)
+	x ^= t ^ t>>28
+	t = 0x3333000033330000 & (x ^ x<<14)
+	x ^= t ^ t>>14
+	t = 0x5500550055005500 & (x ^ x<<7)
+	x ^= t ^ t>>7
+	return Bits(x)
+}
+
+// ReverseRows mirrors the full 8x8 grid in a horizontal line, so the first
+// row becomes the last.
+func (mask Bits) ReverseRows() Bits {
+	return Bits(bits.ReverseBytes64(uint64(mask)))
+}
+
+// ReverseColumns mirrors the full 8x8 grid in a vertical line, so the
+// first column becomes the last.
+func (mask Bits) ReverseColumns() Bits {
+	return Bits(bits.ReverseBytes64(bits.Reverse64(uint64(mask))))
+}
+
+// Rotate90 turns the full 8x8 grid a quarter turn clockwise.  Shapes in
+// the upper left corner end up in the upper right, so Normalize moves them
+// back.
+func (mask Bits) Rotate90() Bits {
+	return mask.Transpose().ReverseColumns()
+}
+
+// Grid draws the first rows and cols of the mask as text, one line for
+// each row, with '#' for the cells set and '.' for the others.
+func (mask Bits) Grid(rows int, cols int) string {
+	var sb strings.Builder
+	for r := 0; r < rows; r++ {
+		for c := 0; c < cols; c++ {
+			if mask&Cell(r, c) != 0 {
+				sb.WriteByte('#')
+			} else {
+				sb.WriteByte('.')
+			}
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// ParseGrid builds a mask from text in the form drawn by Grid.  Leading
+// and trailing space on each line is ignored, as are blank lines, so grids
+// can be indented in Go source.
+func ParseGrid(text string) (Bits, error) {
+	mask := Bits(0)
+	r := 0
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if r == 8 || len(line) > 8 {
+			return 0, fmt.Errorf("grid is larger than 8x8")
+		}
+		for c, cell := range line {
+			switch cell {
+			case '#':
+				mask |= Cell(r, c)
+			case '.':
+			default:
+				return 0, fmt.Errorf("grid row %d has %q, not '#' or '.'",
+					r+1, cell)
+			}
+		}
+		r++
+	}
+	return mask, nil
+}
@@ -58,3 +58,132 @@ func TestZero(t *testing.T) {
 	testTranslate(t, Bits(0xf0f0f0f000000000), 8, 8, Bits(0),
 		"move off the board should be zero")
 }
+
+func TestMarshalText(t *testing.T) {
+	for _, m := range []Bits{0, 1, 0xf0f0f0f000000000, 0xffffffffffffffff} {
+		text, err := m.MarshalText()
+		if err != nil {
+			t.Fatalf("MarshalText(%v) failed: %v", m, err)
+		}
+		var got Bits
+		if err := got.UnmarshalText(text); err != nil || got != m {
+			t.Errorf("UnmarshalText(%s) = %v, %v", text, got, err)
+		}
+	}
+	var m Bits
+	if err := m.UnmarshalText([]byte("0xnotamask")); err == nil {
+		t.Errorf("UnmarshalText should reject invalid text")
+	}
+}
+
+// testMasks are masks with no symmetry, along with the empty and full
+// masks, for checking transforms against cell by cell versions.
+var testMasks = []Bits{0, 0xffffffffffffffff, 0x8000000000000001,
+	0xc0e0000000000000, 0x0123456789abcdef, 0x00003c2418000000}
+
+func TestCells(t *testing.T) {
+	for _, m := range testMasks {
+		got := Bits(0)
+		n := 0
+		lastRow, lastCol := -1, 7
+		for r, c := range m.Cells() {
+			if r < lastRow || r == lastRow && c <= lastCol {
+				t.Errorf("%v: cell (%d, %d) out of order", m, r, c)
+			}
+			lastRow, lastCol = r, c
+			got |= Cell(r, c)
+			n++
+		}
+		if got != m || n != m.Count() {
+			t.Errorf("%v: %d cells make %v, Count() = %d", m, n, got,
+				m.Count())
+		}
+	}
+	// Stopping early must not visit any more cells.
+	n := 0
+	for range Bits(0xff).Cells() {
+		if n++; n == 3 {
+			break
+		}
+	}
+	if n != 3 {
+		t.Errorf("visited %d cells after stopping at 3", n)
+	}
+}
+
+func TestBoundingBox(t *testing.T) {
+	tests := []struct {
+		m                      Bits
+		row, col, nrows, ncols int
+	}{
+		{0, 0, 0, 0, 0},
+		{0xffffffffffffffff, 0, 0, 8, 8},
+		{0x00003c2418000000, 2, 2, 3, 4},
+		{0x0000000000000001, 7, 7, 1, 1},
+	}
+	for _, tt := range tests {
+		row, col, nrows, ncols := tt.m.BoundingBox()
+		if row != tt.row || col != tt.col || nrows != tt.nrows ||
+			ncols != tt.ncols {
+			t.Errorf("%v.BoundingBox() = %d, %d, %d, %d", tt.m, row, col,
+				nrows, ncols)
+		}
+	}
+	if got := Bits(0x00003c2418000000).Normalize(); got != 0xf090600000000000 {
+		t.Errorf("Normalize() = %v", got)
+	}
+}
+
+func TestTransforms(t *testing.T) {
+	// Each transform maps the cell at (r, c) to a new cell.
+	transforms := map[string]struct {
+		apply func(Bits) Bits
+		cell  func(r, c int) Bits
+	}{
+		"Transpose": {Bits.Transpose,
+			func(r, c int) Bits { return Cell(c, r) }},
+		"ReverseRows": {Bits.ReverseRows,
+			func(r, c int) Bits { return Cell(7-r, c) }},
+		"ReverseColumns": {Bits.ReverseColumns,
+			func(r, c int) Bits { return Cell(r, 7-c) }},
+		"Rotate90": {Bits.Rotate90,
+			func(r, c int) Bits { return Cell(c, 7-r) }},
+	}
+	for name, tr := range transforms {
+		for _, m := range testMasks {
+			want := Bits(0)
+			for r, c := range m.Cells() {
+				want |= tr.cell(r, c)
+			}
+			if got := tr.apply(m); got != want {
+				t.Errorf("%s(%v) = %v, want %v", name, m, got, want)
+			}
+		}
+	}
+}
+
+func TestGrid(t *testing.T) {
+	m := Bits(0x00003c2418000000)
+	want := "......\n......\n..####\n..#..#\n...##.\n"
+	if got := m.Grid(5, 6); got != want {
+		t.Errorf("Grid(5, 6) =\n%s\nwant\n%s", got, want)
+	}
+	for _, m := range testMasks {
+		got, err := ParseGrid(m.Grid(8, 8))
+		if err != nil || got != m {
+			t.Errorf("ParseGrid(%v.Grid(8, 8)) = %v, %v", m, got, err)
+		}
+	}
+	got, err := ParseGrid(`
+		.#.
+		###
+	`)
+	if err != nil || got != 0x40e0000000000000 {
+		t.Errorf("ParseGrid of indented grid = %v, %v", got, err)
+	}
+	for _, bad := range []string{"#x#", "#########", "#\n#\n#\n#\n#\n#\n#\n#\n#"} {
+		if _, err := ParseGrid(bad); err == nil {
+			t.Errorf("ParseGrid(%q) should fail", bad)
+		}
+	}
+}
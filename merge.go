@@ -0,0 +1,79 @@
+// -*- tab-width: 4; -*-
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// readSolutions parses the solutions printed by a shapepuzzle run: every
+// board grid which follows a "Solution found." line.  Each solution is
+// returned as its grid text, which identifies it uniquely.
+func readSolutions(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	solutions := []string{}
+	grid := ""
+	inSolution := false
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if inSolution && strings.HasPrefix(line, "[") &&
+			strings.HasSuffix(line, "]") {
+			grid += line + "\n"
+			continue
+		}
+		if grid != "" {
+			solutions = append(solutions, grid)
+			grid = ""
+		}
+		inSolution = (line == "Solution found.")
+	}
+	if grid != "" {
+		solutions = append(solutions, grid)
+	}
+	return solutions, scanner.Err()
+}
+
+// merge combines the output of several shapepuzzle runs, such as the
+// shards of one search, and prints every distinct solution once followed
+// by the totals.  It returns the exit status for the program.
+func merge(paths []string) int {
+
+	if len(paths) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: shapepuzzle merge FILE...")
+		return 2
+	}
+	seen := map[string]bool{}
+	nread := 0
+	counts := []string{}
+	for _, path := range paths {
+		solutions, err := readSolutions(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		for _, grid := range solutions {
+			if !seen[grid] {
+				seen[grid] = true
+				fmt.Printf("Solution found.\n%s\n", grid)
+			}
+		}
+		nread += len(solutions)
+		counts = append(counts,
+			fmt.Sprintf("%s: %d solutions", path, len(solutions)))
+	}
+	for _, c := range counts {
+		fmt.Println(c)
+	}
+	fmt.Printf("%d solutions found in %d files, %d duplicates.\n",
+		len(seen), len(paths), nread-len(seen))
+	return 0
+}
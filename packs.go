@@ -0,0 +1,85 @@
+// -*- tab-width: 4; -*-
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/garyjg/shapepuzzle/board"
+	"github.com/garyjg/shapepuzzle/catalog"
+	"github.com/garyjg/shapepuzzle/puzzle"
+)
+
+// loadPuzzle returns the puzzle in the catalog with the given name, or
+// else the puzzle defined in the file of that name.
+func loadPuzzle(name string) (*puzzle.Puzzle, error) {
+	if entry, ok := catalog.Lookup(name); ok {
+		return entry.Puzzle()
+	}
+	return puzzle.Load(name)
+}
+
+// pack finds the packings of a puzzle's pieces which cover the most cells
+// of its board, or with -fit the smallest board which holds every piece.
+func pack(args []string) int {
+
+	fs := flag.NewFlagSet("pack", flag.ContinueOnError)
+	keep := fs.Int("keep", 1, "number of best packings to print")
+	nodes := fs.Int64("nodes", 0,
+		"stop the search after this many nodes, 0 for no limit")
+	fit := fs.Bool("fit", false,
+		"find the smallest board which holds every piece, ignoring the "+
+			"puzzle's board")
+	logLevel := fs.String("log-level", "warn",
+		"log messages at this level or above: debug, info, warn or error")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: shapepuzzle pack [flags] NAME|FILE")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+	logger, err := newLogger(*logLevel)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	p, err := loadPuzzle(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	opts := board.PackOptions{Keep: *keep, MaxNodes: *nodes, Logger: logger}
+	var packing board.Packing
+	if *fit {
+		packing, err = board.SmallestBoard(p.Shapes, 8, 8, opts)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		b := packing.Boards[0]
+		fmt.Printf("Smallest board is %dx%d.\n%s\n", b.NumRows(), b.NumCols(), b)
+		return 0
+	}
+	packing = p.Board.Pack(p.Shapes, opts)
+	for _, b := range packing.Boards {
+		fmt.Printf("Packing found.\n%s\n", b)
+	}
+	fmt.Printf("Best packing covers %d of %d cells.\n", packing.Covered,
+		packing.Bound)
+	if packing.Optimal {
+		fmt.Printf("Search completed in %d nodes: no packing covers more.\n",
+			packing.Nodes)
+	} else {
+		fmt.Printf("Search stopped after %d nodes: a better packing may "+
+			"exist.\n", packing.Nodes)
+	}
+	return 0
+}
@@ -0,0 +1,433 @@
+// -*- tab-width: 4; -*-
+
+// Package play lets a person solve a puzzle by hand in a terminal.  A Game
+// holds the pieces placed so far and the piece being moved around the
+// board, and takes its commands one key at a time, so that Run only has to
+// read keys and draw the Game after each one.
+package play
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/garyjg/shapepuzzle/board"
+	"github.com/garyjg/shapepuzzle/mask"
+	"github.com/garyjg/shapepuzzle/shape"
+)
+
+// Game is the state of a puzzle being solved by hand: the board with the
+// pieces placed so far, and the piece in hand, with its orientation from
+// the shape's Permutations and its position on the board.  The indexes of
+// the shapes placed, their placements and the boards before each one make
+// up the history which Undo goes back through.
+type Game struct {
+	board   board.Board
+	shapes  []shape.Shape
+	perms   [][]shape.Shape
+	gaps    []shape.Shape
+	placed  []int
+	moves   []shape.Shape
+	history []board.Board
+	piece   int
+	perm    int
+	row     int
+	col     int
+	message string
+}
+
+// NewGame starts a Game of filling the board with the shapes, with the
+// first shape in hand.
+func NewGame(b board.Board, shapes []shape.Shape) *Game {
+	g := &Game{board: b, shapes: shapes, gaps: board.GapShapes(b)}
+	for _, s := range shapes {
+		g.perms = append(g.perms, s.Permutations())
+	}
+	if len(shapes) > 0 {
+		g.hold(0, 0)
+	}
+	g.message = "Pick a piece with tab, move it with the arrow keys, and " +
+		"place it with enter."
+	return g
+}
+
+// Board returns the board with the pieces placed so far.
+func (g *Game) Board() board.Board {
+	return g.board
+}
+
+// Solved returns true once every piece is on the board.
+func (g *Game) Solved() bool {
+	return len(g.placed) == len(g.shapes)
+}
+
+// Message returns the result of the last command.
+func (g *Game) Message() string {
+	return g.message
+}
+
+// isPlaced returns true if shape i is on the board.
+func (g *Game) isPlaced(i int) bool {
+	for _, p := range g.placed {
+		if p == i {
+			return true
+		}
+	}
+	return false
+}
+
+// held returns the piece in hand at its position on the board, or false
+// if it does not fit there, as when no orientation of it fits the board.
+func (g *Game) held() (shape.Shape, bool) {
+	return g.board.Translate(g.perms[g.piece][g.perm], g.row, g.col)
+}
+
+// fits returns true if the orientation p is no larger than the board.
+func (g *Game) fits(p shape.Shape) bool {
+	return p.NumRows() <= g.board.NumRows() &&
+		p.NumCols() <= g.board.NumCols()
+}
+
+// Key carries out the command for a key, named as ReadKey names them, and
+// returns false if the key quits the game.
+func (g *Game) Key(key string) bool {
+	switch key {
+	case "q", "ctrl-c":
+		return false
+	case "tab", "n":
+		g.Select(1)
+	case "shift-tab", "p":
+		g.Select(-1)
+	case "up":
+		g.Move(-1, 0)
+	case "down":
+		g.Move(1, 0)
+	case "left":
+		g.Move(0, -1)
+	case "right":
+		g.Move(0, 1)
+	case "r":
+		g.Turn()
+	case "f":
+		g.Flip()
+	case "enter", " ":
+		g.Place()
+	case "u":
+		g.Undo()
+	case "h":
+		g.Hint()
+	case "c":
+		g.CheckSolvable()
+	default:
+		g.message = fmt.Sprintf("Unknown key %q.", key)
+	}
+	return true
+}
+
+// Select takes the next piece not yet placed, counting forward if step
+// is 1 or backward if it is -1.
+func (g *Game) Select(step int) {
+	if g.Solved() {
+		g.message = "Every piece is placed."
+		return
+	}
+	n := len(g.shapes)
+	for i := (g.piece + step + n) % n; ; i = (i + step + n) % n {
+		if !g.isPlaced(i) {
+			g.hold(i, 0)
+			g.message = fmt.Sprintf("Holding piece %s.", g.name(i))
+			return
+		}
+	}
+}
+
+// hold takes shape i in orientation perm, or the next orientation which
+// fits the board if that one does not, keeping it on the board.
+func (g *Game) hold(i int, perm int) {
+	n := len(g.perms[i])
+	for o := perm; o < perm+n; o++ {
+		if g.fits(g.perms[i][o%n]) {
+			perm = o % n
+			break
+		}
+	}
+	g.piece, g.perm = i, perm
+	g.Move(0, 0)
+}
+
+// name returns the name of shape i, or its ID if it has no name.
+func (g *Game) name(i int) string {
+	if name := g.shapes[i].Name(); name != "" {
+		return name
+	}
+	return fmt.Sprint(g.shapes[i].ID())
+}
+
+// Move moves the piece in hand by dr rows and dc columns, as far as the
+// edges of the board allow, or round to the other side across the edges
+// of a cylinder or torus which join.  A piece which does not fit the
+// board stays where it is.
+func (g *Game) Move(dr int, dc int) {
+	p := g.perms[g.piece][g.perm]
+	nrows, ncols := g.board.NumRows(), g.board.NumCols()
+	row, col := g.row, g.col
+	if g.board.Topology() == board.Torus {
+		row = ((row+dr)%nrows + nrows) % nrows
+	} else {
+		row = max(0, min(row+dr, nrows-p.NumRows()))
+	}
+	if g.board.Topology() != board.Plane {
+		col = ((col+dc)%ncols + ncols) % ncols
+	} else {
+		col = max(0, min(col+dc, ncols-p.NumCols()))
+	}
+	g.message = ""
+	if _, ok := g.board.Translate(p, row, col); !ok {
+		g.message = fmt.Sprintf("Piece %s does not fit on the board.",
+			g.name(g.piece))
+		return
+	}
+	g.row, g.col = row, col
+}
+
+// find takes shape i in hand in the orientation and position which cover
+// the cells of m, and returns false if there is none.
+func (g *Game) find(i int, m mask.Bits) bool {
+	for o, perm := range g.perms[i] {
+		for r := 0; r < g.board.NumRows(); r++ {
+			for c := 0; c < g.board.NumCols(); c++ {
+				if p, ok := g.board.Translate(perm, r, c); ok &&
+					p.Mask() == m {
+					g.piece, g.perm, g.row, g.col = i, o, r, c
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// orient turns the piece in hand to the orientation in its Permutations
+// whose mask is m moved to the origin, and returns false if that
+// orientation does not fit on the board.
+func (g *Game) orient(m mask.Bits) bool {
+	m = m.Normalize()
+	for i, p := range g.perms[g.piece] {
+		if p.Mask() == m {
+			if !g.fits(p) {
+				return false
+			}
+			g.hold(g.piece, i)
+			return true
+		}
+	}
+	return false
+}
+
+// Turn turns the piece in hand a quarter turn clockwise, or further if it
+// does not fit on the board turned that far.
+func (g *Game) Turn() {
+	m := g.perms[g.piece][g.perm].Mask()
+	for i := 0; i < 3; i++ {
+		if m = m.Rotate90(); g.orient(m) {
+			break
+		}
+	}
+	g.message = fmt.Sprintf("Orientation %d of %d.", g.perm+1,
+		len(g.perms[g.piece]))
+}
+
+// Flip turns the piece in hand upside down.
+func (g *Game) Flip() {
+	g.orient(g.perms[g.piece][g.perm].Mask().ReverseRows())
+	g.message = fmt.Sprintf("Orientation %d of %d.", g.perm+1,
+		len(g.perms[g.piece]))
+}
+
+// Place puts the piece in hand on the board, unless it is already there,
+// it overlaps another piece, or it leaves a gap which no piece could
+// fill.  The next piece not yet placed is then taken in hand.
+func (g *Game) Place() {
+	if g.Solved() {
+		g.message = "Every piece is placed."
+		return
+	}
+	if g.isPlaced(g.piece) {
+		g.message = fmt.Sprintf("Piece %s is already placed.", g.name(g.piece))
+		return
+	}
+	p, ok := g.held()
+	if !ok {
+		g.message = fmt.Sprintf("Piece %s does not fit on the board.",
+			g.name(g.piece))
+		return
+	}
+	if p.Mask()&g.board.Mask() != 0 {
+		g.message = fmt.Sprintf("Piece %s overlaps the pieces on the board.",
+			g.name(g.piece))
+		return
+	}
+	nb := g.board.Place(p)
+	if board.RejectBoard(nb, g.gaps) {
+		g.message = fmt.Sprintf("Piece %s leaves a gap no piece can fill.",
+			g.name(g.piece))
+		return
+	}
+	g.history = append(g.history, g.board)
+	g.board = nb
+	g.placed = append(g.placed, g.piece)
+	g.moves = append(g.moves, p)
+	if g.Solved() {
+		g.message = "Solved!"
+		return
+	}
+	placed := g.name(g.piece)
+	g.Select(1)
+	g.message = fmt.Sprintf("Placed piece %s.", placed)
+}
+
+// Undo takes the last piece placed back into hand, where it was.
+func (g *Game) Undo() {
+	n := len(g.placed)
+	if n == 0 {
+		g.message = "No pieces to take back."
+		return
+	}
+	i, last := g.placed[n-1], g.moves[n-1]
+	g.board = g.history[n-1]
+	g.placed, g.moves, g.history = g.placed[:n-1], g.moves[:n-1],
+		g.history[:n-1]
+	g.find(i, last.Mask())
+	g.message = fmt.Sprintf("Took back piece %s.", g.name(i))
+}
+
+// solve returns the first solution the cell search finds for the pieces
+// not yet placed, if there is one.  The search logs nothing, since its
+// messages would be drawn over the game.
+func (g *Game) solve() (board.Board, bool) {
+	rest := []shape.Shape{}
+	for i, s := range g.shapes {
+		if !g.isPlaced(i) {
+			rest = append(rest, s)
+		}
+	}
+	opts := board.Options{Strategy: board.ConstrainedCell, MaxSolutions: 1,
+		Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	solutions, err := g.board.SolveOptions(rest, opts)
+	if err != nil {
+		return board.Board{}, false
+	}
+	for b := range solutions {
+		return b, true
+	}
+	return board.Board{}, false
+}
+
+// Hint takes in hand the piece which covers the first empty cell in a
+// solution from the pieces placed so far, in its place in that solution.
+func (g *Game) Hint() {
+	if g.Solved() {
+		g.message = "Every piece is placed."
+		return
+	}
+	sol, ok := g.solve()
+	if !ok {
+		g.message = "No solution from here: take back a piece with u."
+		return
+	}
+	grid := sol.Grid()
+	id := -1
+	for r, row := range grid {
+		for c, cellID := range row {
+			if id < 0 && g.board.Mask()&mask.Cell(r, c) == 0 {
+				id = cellID
+			}
+		}
+	}
+	m := mask.Bits(0)
+	for r, row := range grid {
+		for c, cellID := range row {
+			if cellID == id {
+				m |= mask.Cell(r, c)
+			}
+		}
+	}
+	for i, s := range g.shapes {
+		if s.ID() == id {
+			g.find(i, m)
+			g.message = fmt.Sprintf("Hint: piece %s goes here.", g.name(i))
+			return
+		}
+	}
+}
+
+// CheckSolvable reports whether the pieces left can still fill the board.
+func (g *Game) CheckSolvable() {
+	if _, ok := g.solve(); ok {
+		g.message = "The pieces left can still fill the board."
+	} else {
+		g.message = "The pieces left cannot fill the board: take back a " +
+			"piece with u."
+	}
+}
+
+// Help lists the keys the Game understands.
+const Help = "tab/n p: next/previous piece  arrows: move  r: turn  f: flip  " +
+	"enter: place  u: undo  h: hint  c: check  q: quit"
+
+// pieceColor returns the 256-color palette entry for the cells of shape
+// id.
+func pieceColor(id int) int {
+	return 16 + (id*47)%216
+}
+
+// Draw draws the board as Board.String does, a row of cells in brackets
+// with the ID of the piece on each cell, with the piece in hand drawn over
+// it as ## and the list of pieces below.  With color, each piece is drawn
+// in its own color and the piece in hand in reverse video, or red where it
+// overlaps a piece.
+func (g *Game) Draw(color bool) string {
+
+	grid := g.board.Grid()
+	held := mask.Bits(0)
+	if p, ok := g.held(); ok && !g.Solved() && !g.isPlaced(g.piece) {
+		held = p.Mask()
+	}
+	var sb strings.Builder
+	for r, row := range grid {
+		sb.WriteString("[")
+		for c, id := range row {
+			cell := mask.Cell(r, c)
+			text := fmt.Sprintf(" %2d", id)
+			if held&cell != 0 {
+				text = " ##"
+			}
+			switch {
+			case !color:
+			case held&cell != 0 && g.board.Mask()&cell != 0:
+				text = "\x1b[41m" + text + "\x1b[0m"
+			case held&cell != 0:
+				text = "\x1b[7m" + text + "\x1b[0m"
+			case id != 0:
+				text = fmt.Sprintf("\x1b[30;48;5;%dm%s\x1b[0m", pieceColor(id),
+					text)
+			}
+			sb.WriteString(text)
+		}
+		sb.WriteString("]\n")
+	}
+	sb.WriteString("\nPieces:")
+	for i := range g.shapes {
+		name := g.name(i)
+		if g.isPlaced(i) {
+			name = "(" + name + ")"
+		}
+		if i == g.piece && !g.Solved() {
+			name = ">" + name
+		}
+		sb.WriteString(" " + name)
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
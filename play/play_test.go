@@ -0,0 +1,179 @@
+// -*- tab-width: 4; -*-
+
+package play
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+
+	"github.com/garyjg/shapepuzzle/board"
+	"github.com/garyjg/shapepuzzle/mask"
+	"github.com/garyjg/shapepuzzle/shape"
+)
+
+func newGame() *Game {
+	shapes := shape.MakeShapes([][][]int{
+		{{1, 1, 1, 1}, {1, 0, 0, 0}},
+		{{0, 0, 0, 1}, {1, 1, 1, 1}},
+		{{1, 1, 1, 1, 1}}})
+	for i, name := range []string{"A", "B", "I"} {
+		shapes[i] = shapes[i].WithName(name)
+	}
+	return NewGame(board.NewBoard(3, 5), shapes)
+}
+
+func TestPlace(t *testing.T) {
+
+	g := newGame()
+	// The second piece in the corner closes off the three cells above it.
+	g.Select(1)
+	g.Place()
+	if !strings.Contains(g.Message(), "gap") || g.Board().Mask() != 0 {
+		t.Errorf("Placed a piece leaving a gap: %s", g.Message())
+	}
+	g.Flip()
+	g.Place()
+	if g.Board().NumShapes() != 1 || !strings.Contains(g.Message(), "Placed") {
+		t.Fatalf("Could not place the flipped piece: %s", g.Message())
+	}
+
+	// The next piece is taken in hand where the last one was.
+	g.Place()
+	if g.Board().NumShapes() != 1 || !strings.Contains(g.Message(), "overlaps") {
+		t.Errorf("Placed an overlapping piece: %s", g.Message())
+	}
+
+	g.Undo()
+	if g.Board().NumShapes() != 0 || g.piece != 1 {
+		t.Errorf("Undo left %d pieces, holding piece %d", g.Board().NumShapes(),
+			g.piece)
+	}
+	if g.Key("q") {
+		t.Errorf("q did not quit")
+	}
+}
+
+func TestHint(t *testing.T) {
+
+	g := newGame()
+	g.CheckSolvable()
+	if !strings.Contains(g.Message(), "can still") {
+		t.Errorf("New game is not solvable: %s", g.Message())
+	}
+	for i := 0; i < 3 && !g.Solved(); i++ {
+		g.Hint()
+		if !strings.HasPrefix(g.Message(), "Hint") {
+			t.Fatalf("No hint: %s", g.Message())
+		}
+		g.Place()
+	}
+	if g.Message() != "Solved!" || g.Board().Mask() != g.Board().RegionMask() {
+		t.Errorf("Following the hints did not solve the puzzle:\n%s",
+			g.Draw(false))
+	}
+}
+
+func TestTurn(t *testing.T) {
+
+	g := newGame()
+	g.Turn()
+	if p, _ := g.held(); g.perm == 0 || p.NumRows() != 2 {
+		t.Errorf("Turned piece is orientation %d:\n%s", g.perm, g.Draw(false))
+	}
+	g.Turn()
+	if g.perm != 0 {
+		t.Errorf("Piece turned twice is orientation %d", g.perm)
+	}
+	g.Select(1)
+	g.Select(1)
+	if g.piece != 2 {
+		t.Fatalf("Holding piece %d, want 2", g.piece)
+	}
+	// The bar only fits the board lying down.
+	g.Turn()
+	if g.perm != 0 {
+		t.Errorf("Bar turned on its end to orientation %d", g.perm)
+	}
+	g.Move(5, 5)
+	if g.row != 2 || g.col != 0 {
+		t.Errorf("Bar moved off the board to %d, %d", g.row, g.col)
+	}
+	if d := g.Draw(false); !strings.Contains(d, "[ ## ## ## ## ##]") ||
+		!strings.Contains(d, "Pieces: A B >I") {
+		t.Errorf("Draw:\n%s", d)
+	}
+}
+
+func TestFit(t *testing.T) {
+
+	shapes := shape.MakeShapes([][][]int{
+		{{1}, {1}, {1}},
+		{{1, 1}, {1, 1}}})
+	g := NewGame(board.NewBoard(1, 3), shapes)
+
+	// The bar is taken in hand lying down, the only way it fits.
+	if p, ok := g.held(); !ok || p.NumCols() != 3 {
+		t.Errorf("Holding a bar which does not fit:\n%s", g.Draw(false))
+	}
+	g.Select(1)
+	g.Move(0, 1)
+	g.Place()
+	if g.Board().Mask() != 0 || !strings.Contains(g.Message(), "not fit") {
+		t.Errorf("Placed a square which does not fit: %s", g.Message())
+	}
+	if d := g.Draw(false); strings.Contains(d, "##") {
+		t.Errorf("Drew a square which does not fit:\n%s", d)
+	}
+	g.Select(1)
+	g.Place()
+	if g.Board().NumShapes() != 1 {
+		t.Errorf("Could not place the bar: %s", g.Message())
+	}
+}
+
+func TestReadKey(t *testing.T) {
+	// Alt-u arrives as escape then u.
+	r := bufio.NewReader(strings.NewReader(
+		"\x1b[A\x1b[D\r\tx\x1b[Z\x1bu\x1b"))
+	for _, want := range []string{"up", "left", "enter", "tab", "x",
+		"shift-tab", "esc", "u", "esc"} {
+		if key, err := ReadKey(r); err != nil || key != want {
+			t.Errorf("ReadKey = %q, %v, want %q", key, err, want)
+		}
+	}
+}
+
+func TestWrap(t *testing.T) {
+
+	shapes := shape.MakeShapes([][][]int{
+		{{1, 1, 1, 1}, {1, 0, 0, 0}},
+		{{0, 0, 0, 1}, {1, 1, 1, 1}}})
+	b := board.NewBoard(2, 5).WithTopology(board.Cylinder)
+	g := NewGame(b, shapes)
+
+	// On a cylinder the piece moves round the left edge to the right.
+	g.Move(0, -1)
+	if g.col != 4 {
+		t.Fatalf("Piece moved left to column %d, want 4", g.col)
+	}
+	g.Place()
+	want := mask.Cell(0, 4) | mask.Cell(0, 0) | mask.Cell(0, 1) |
+		mask.Cell(0, 2) | mask.Cell(1, 4)
+	if g.Board().Mask() != want {
+		t.Fatalf("Placed %v, want %v: %s", g.Board().Mask(), want,
+			g.Message())
+	}
+
+	g.Hint()
+	g.Place()
+	if !g.Solved() {
+		t.Fatalf("Hint did not solve the puzzle: %s", g.Message())
+	}
+	g.Undo()
+	g.Undo()
+	if p, _ := g.held(); g.piece != 0 || p.Mask() != want {
+		t.Errorf("Undo holds piece %d at %v, want 0 at %v", g.piece,
+			p.Mask(), want)
+	}
+}
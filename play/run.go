@@ -0,0 +1,86 @@
+// -*- tab-width: 4; -*-
+
+package play
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+)
+
+// ReadKey reads one key press from a terminal in raw mode and names it:
+// "up", "down", "left" and "right" for the arrow keys, "enter", "tab",
+// "shift-tab", "ctrl-c" and "esc", or else the character typed.
+func ReadKey(r *bufio.Reader) (string, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return "", err
+	}
+	switch b {
+	case '\r', '\n':
+		return "enter", nil
+	case '\t':
+		return "tab", nil
+	case 3:
+		return "ctrl-c", nil
+	case 0x1b:
+		// An escape sequence arrives all at once, while a lone escape
+		// key has nothing buffered after it.
+		if r.Buffered() == 0 {
+			return "esc", nil
+		}
+		// Alt and a key sends escape then the key, which is read next.
+		if b, err = r.ReadByte(); err != nil {
+			return "esc", err
+		}
+		if b != '[' {
+			r.UnreadByte()
+			return "esc", nil
+		}
+		if b, err = r.ReadByte(); err != nil {
+			return "esc", err
+		}
+		switch b {
+		case 'A':
+			return "up", nil
+		case 'B':
+			return "down", nil
+		case 'C':
+			return "right", nil
+		case 'D':
+			return "left", nil
+		case 'Z':
+			return "shift-tab", nil
+		}
+		return "esc", nil
+	}
+	r.UnreadByte()
+	c, _, err := r.ReadRune()
+	return string(c), err
+}
+
+// Run plays the Game on a terminal, reading keys from in, which is put in
+// raw mode until the game ends, and drawing the game in color on out after
+// every key.
+func Run(g *Game, in *os.File, out io.Writer) error {
+
+	restore, err := makeRaw(in)
+	if err != nil {
+		return err
+	}
+	defer restore()
+
+	r := bufio.NewReader(in)
+	for {
+		fmt.Fprintf(out, "\x1b[H\x1b[2J%s\n%s\n%s\n", g.Draw(true),
+			g.Message(), Help)
+		key, err := ReadKey(r)
+		if err != nil {
+			return err
+		}
+		if !g.Key(key) {
+			return nil
+		}
+	}
+}
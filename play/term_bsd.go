@@ -0,0 +1,13 @@
+// -*- tab-width: 4; -*-
+
+//go:build darwin || freebsd || netbsd || openbsd
+
+package play
+
+import "syscall"
+
+// The ioctl requests which get and set the terminal settings.
+const (
+	ioctlGetTermios = syscall.TIOCGETA
+	ioctlSetTermios = syscall.TIOCSETA
+)
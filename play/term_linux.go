@@ -0,0 +1,11 @@
+// -*- tab-width: 4; -*-
+
+package play
+
+import "syscall"
+
+// The ioctl requests which get and set the terminal settings.
+const (
+	ioctlGetTermios = syscall.TCGETS
+	ioctlSetTermios = syscall.TCSETS
+)
@@ -0,0 +1,18 @@
+// -*- tab-width: 4; -*-
+
+//go:build !(linux || darwin || freebsd || netbsd || openbsd)
+
+package play
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+)
+
+// makeRaw fails, since raw terminal input is only written for the systems
+// with termios.
+func makeRaw(f *os.File) (func() error, error) {
+	return nil, fmt.Errorf("raw terminal input is not supported on %s",
+		runtime.GOOS)
+}
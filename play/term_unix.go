@@ -0,0 +1,46 @@
+// -*- tab-width: 4; -*-
+
+//go:build linux || darwin || freebsd || netbsd || openbsd
+
+package play
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// termios gets or sets the terminal settings of the file with the ioctl
+// request req.
+func termios(f *os.File, req uintptr, t *syscall.Termios) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), req,
+		uintptr(unsafe.Pointer(t)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// makeRaw puts the terminal in raw mode, so that every key is read as soon
+// as it is pressed, without echo, line editing or signals, and returns the
+// function which restores the terminal.  Output processing is left on, so
+// newlines still start a new line.
+func makeRaw(f *os.File) (func() error, error) {
+	var old syscall.Termios
+	if err := termios(f, ioctlGetTermios, &old); err != nil {
+		return nil, err
+	}
+	raw := old
+	raw.Iflag &^= syscall.ICRNL | syscall.IXON | syscall.BRKINT |
+		syscall.INPCK | syscall.ISTRIP
+	raw.Lflag &^= syscall.ECHO | syscall.ICANON | syscall.ISIG |
+		syscall.IEXTEN
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+	if err := termios(f, ioctlSetTermios, &raw); err != nil {
+		return nil, err
+	}
+	return func() error {
+		return termios(f, ioctlSetTermios, &old)
+	}, nil
+}
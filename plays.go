@@ -0,0 +1,47 @@
+// -*- tab-width: 4; -*-
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/garyjg/shapepuzzle/play"
+)
+
+// playPuzzle lets the user solve a puzzle by hand in the terminal.
+func playPuzzle(args []string) int {
+
+	fs := flag.NewFlagSet("play", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: shapepuzzle play NAME|FILE")
+		fmt.Fprintln(os.Stderr, play.Help)
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+	if !isTerminal(os.Stdin) || !isTerminal(os.Stdout) {
+		fmt.Fprintln(os.Stderr, "play needs a terminal for input and output")
+		return 1
+	}
+	p, err := loadPuzzle(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	g := play.NewGame(p.Board, p.Shapes)
+	if err := play.Run(g, os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if g.Solved() {
+		fmt.Println("Solved!")
+	}
+	return 0
+}
@@ -0,0 +1,83 @@
+// -*- tab-width: 4; -*-
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/garyjg/shapepuzzle/board"
+)
+
+// progressLine shows the progress of a search on one line of a terminal,
+// rewriting the line in place with each update.  It stays quiet when its
+// file is not a terminal, so nothing is written when output is piped.
+type progressLine struct {
+	mu      sync.Mutex
+	out     *os.File
+	enabled bool
+	width   int
+}
+
+func newProgressLine(out *os.File) *progressLine {
+	return &progressLine{out: out, enabled: isTerminal(out)}
+}
+
+// isTerminal reports whether the file is a character device, such as a
+// terminal, rather than a pipe or a regular file.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// update replaces the progress line with the given progress.
+func (pl *progressLine) update(p board.Progress) {
+	if !pl.enabled {
+		return
+	}
+	text := fmt.Sprintf("%s nodes (%s/s), %d solutions, %.1f%% done "+
+		"(%d/%d first placements), %v", humanCount(float64(p.Nodes)),
+		humanCount(p.NodesPerSecond()), p.Solutions, 100*p.Fraction(),
+		p.FirstDone, p.FirstTotal, p.Elapsed.Round(1e8))
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	pad := ""
+	if len(text) < pl.width {
+		pad = strings.Repeat(" ", pl.width-len(text))
+	}
+	fmt.Fprintf(pl.out, "\r%s%s", text, pad)
+	pl.width = len(text)
+	if p.Done {
+		fmt.Fprintln(pl.out)
+		pl.width = 0
+	}
+}
+
+// clear erases the progress line, so that other output can be written.
+// The next update draws it again.
+func (pl *progressLine) clear() {
+	if !pl.enabled {
+		return
+	}
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	if pl.width > 0 {
+		fmt.Fprintf(pl.out, "\r%s\r", strings.Repeat(" ", pl.width))
+		pl.width = 0
+	}
+}
+
+// humanCount abbreviates large counts with k, M and G suffixes.
+func humanCount(n float64) string {
+	switch {
+	case n >= 1e9:
+		return fmt.Sprintf("%.1fG", n/1e9)
+	case n >= 1e6:
+		return fmt.Sprintf("%.1fM", n/1e6)
+	case n >= 1e3:
+		return fmt.Sprintf("%.1fk", n/1e3)
+	}
+	return fmt.Sprintf("%.0f", n)
+}
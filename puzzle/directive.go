@@ -0,0 +1,166 @@
+// -*- tab-width: 4; -*-
+
+package puzzle
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/garyjg/shapepuzzle/cover"
+)
+
+// Directive is a keyword line from a puzzle file and the grid lines
+// following it.
+type Directive struct {
+	Line int
+	Args []string
+	Grid []string
+}
+
+// ReadDirectives splits a puzzle file into its directives, skipping blank
+// lines and comments.  Grid lines hold only '#', '.' and the characters in
+// extra, such as the spaces between the layers of a polycube.  A comment
+// starts with "# ", but a line which is also a grid line, such as "# # #"
+// when extra holds a space, is read as a grid line.
+func ReadDirectives(r io.Reader, extra string) ([]*Directive, error) {
+
+	directives := []*Directive{}
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+		case strings.Trim(line, "#."+extra) == "":
+			if len(directives) == 0 {
+				return nil, fmt.Errorf("line %d: grid without a directive", n)
+			}
+			d := directives[len(directives)-1]
+			d.Grid = append(d.Grid, line)
+		case strings.HasPrefix(line, "# "):
+		default:
+			directives = append(directives,
+				&Directive{Line: n, Args: strings.Fields(line)})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return directives, nil
+}
+
+// Cells converts grid lines into rows of ones for '#' and zeros for any
+// other character.  The rows may have different lengths.
+func Cells(grid []string) [][]int {
+	rows := make([][]int, len(grid))
+	for r, line := range grid {
+		rows[r] = make([]int, len(line))
+		for c, cell := range line {
+			if cell == '#' {
+				rows[r][c] = 1
+			}
+		}
+	}
+	return rows
+}
+
+// Definition is a puzzle read by ParseDefinition: its name, its board of
+// type B, its pieces of type S, and the flags set by directives without
+// arguments, such as one-sided.
+type Definition[B, S any] struct {
+	Name   string
+	Board  B
+	Shapes []S
+	Flags  map[string]bool
+}
+
+// Grammar tells ParseDefinition how to read the puzzle files of a grid
+// other than the square one, such as the hex, tri and cube packages use.
+type Grammar[B, S any] struct {
+	// Extra holds the characters allowed in grid lines besides '#' and '.'.
+	Extra string
+	// Flags are the directives without arguments a file may give.
+	Flags []string
+	// Board makes the board from the arguments of the board directive.
+	Board func(args []string) (B, error)
+	// Piece makes the piece with the given ID from its grid lines.
+	Piece func(id int, grid []string) (S, error)
+	// Name returns a copy of the piece with the given name.
+	Name func(s S, name string) S
+}
+
+// ParseDefinition reads a puzzle file with the name, board and piece
+// directives of the puzzle package and the flags of the grammar.  Only a
+// piece may be followed by a grid, and pieces are searched by the cover
+// package, so there may be at most cover.MaxPieces of them.  Errors give
+// the line number of the directive at fault.
+func ParseDefinition[B, S any](r io.Reader,
+	g Grammar[B, S]) (*Definition[B, S], error) {
+
+	directives, err := ReadDirectives(r, g.Extra)
+	if err != nil {
+		return nil, err
+	}
+	p := &Definition[B, S]{Flags: map[string]bool{}}
+	sized := false
+	for _, d := range directives {
+		var err error
+		switch {
+		case d.Args[0] != "piece" && d.Grid != nil:
+			err = fmt.Errorf("grid without a piece")
+		case d.Args[0] == "name":
+			p.Name = strings.Join(d.Args[1:], " ")
+		case d.Args[0] == "board":
+			if sized {
+				err = fmt.Errorf("board given twice")
+			} else {
+				p.Board, err = g.Board(d.Args[1:])
+				sized = true
+			}
+		case d.Args[0] == "piece":
+			err = p.addPiece(d, g)
+		case len(d.Args) == 1 && contains(g.Flags, d.Args[0]):
+			p.Flags[d.Args[0]] = true
+		default:
+			err = fmt.Errorf("unknown directive %q", d.Args[0])
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", d.Line, err)
+		}
+	}
+	if !sized {
+		return nil, fmt.Errorf("no board given")
+	}
+	if len(p.Shapes) == 0 {
+		return nil, fmt.Errorf("no pieces given")
+	}
+	return p, nil
+}
+
+func (p *Definition[B, S]) addPiece(d *Directive, g Grammar[B, S]) error {
+	if len(d.Args) != 2 {
+		return fmt.Errorf("piece needs a name")
+	}
+	if len(p.Shapes) == cover.MaxPieces {
+		return fmt.Errorf("more than %d pieces", cover.MaxPieces)
+	}
+	if d.Grid == nil {
+		return fmt.Errorf("piece %s has no grid", d.Args[1])
+	}
+	s, err := g.Piece(len(p.Shapes)+1, d.Grid)
+	if err != nil {
+		return fmt.Errorf("piece %s: %v", d.Args[1], err)
+	}
+	p.Shapes = append(p.Shapes, g.Name(s, d.Args[1]))
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+// -*- tab-width: 4; -*-
+
+package puzzle
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// rows is a grammar whose boards are a count of cells and whose pieces
+// are their grid lines joined by slashes.
+var rows = Grammar[int, string]{
+	Extra: " ",
+	Flags: []string{"mirror"},
+	Board: func(args []string) (int, error) {
+		if len(args) != 1 {
+			return 0, fmt.Errorf("board needs a size")
+		}
+		var n int
+		_, err := fmt.Sscan(args[0], &n)
+		return n, err
+	},
+	Piece: func(id int, grid []string) (string, error) {
+		return fmt.Sprint(id, ":", strings.Join(grid, "/")), nil
+	},
+	Name: func(s string, name string) string {
+		return name + s
+	},
+}
+
+func TestParseDefinition(t *testing.T) {
+
+	text := "name two rows\nboard 6\nmirror\n# A comment.\n" +
+		"piece A\n## .#\npiece B\n#\npiece C\n# # #\n"
+	p, err := ParseDefinition(strings.NewReader(text), rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := fmt.Sprint(p.Name, p.Board, p.Shapes, p.Flags)
+	want := "two rows6 [A1:## .# B2:# C3:# # #] map[mirror:true]"
+	if got != want {
+		t.Errorf("parsed %s, want %s", got, want)
+	}
+
+	tests := map[string]string{
+		"board\npiece A\n#\n":           "line 1: board needs a size",
+		"board 2\nboard 2\npiece A\n#":  "line 2: board given twice",
+		"board 2\n##\npiece A\n#\n":     "line 1: grid without a piece",
+		"board 2\npiece A\n":            "line 2: piece A has no grid",
+		"board 2\npiece\n#\n":           "line 2: piece needs a name",
+		"board 2\nmirror 2\npiece A\n#": "line 2: unknown directive",
+		"board 2\none-sided\n":          "line 2: unknown directive",
+		"board 2\n":                     "no pieces",
+		"#\nboard 2\n":                  "line 1: grid without a directive",
+		"board 64\n" + strings.Repeat("piece A\n#\n", 65): "line 130: " +
+			"more than 64 pieces",
+	}
+	for text, want := range tests {
+		_, err := ParseDefinition(strings.NewReader(text), rows)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("ParseDefinition(%q) error %v, want %q", text, err, want)
+		}
+	}
+}
+
+func TestCells(t *testing.T) {
+	got := fmt.Sprint(Cells([]string{"#.#", ".#"}))
+	if want := "[[1 0 1] [0 1]]"; got != want {
+		t.Errorf("Cells got %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,244 @@
+// -*- tab-width: 4; -*-
+
+// Package puzzle reads and writes puzzle definitions: a board, with any
+// holes in it, and the shapes which must fill it.
+//
+// A puzzle file is a list of directives, each one a keyword on a line of
+// its own and usually followed by a grid.  Grids are drawn with '#' for
+// the cells of a shape, or the holes in a board, and '.' for empty cells.
+// Blank lines and lines starting with '#' followed by a space are ignored.
+//
+//	# Pentominoes on an 8x8 board with a hole in the centre.
+//	name scott
+//	board 8 8
+//	........
+//	........
+//	........
+//	...##...
+//	...##...
+//	........
+//	........
+//	........
+//	piece F
+//	.##
+//	##.
+//	.#.
+//
+// The grid after board is optional when the board has no holes.  Pieces
+// are given IDs from 1 in the order they appear.  A topology directive,
+// "topology cylinder" or "topology torus", joins the edges of the board so
+// that pieces wrap around them.
+//
+// The hex, tri and cube packages read the same format through
+// ParseDefinition, which leaves each of them to make its own boards and
+// pieces from the directives.
+package puzzle
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/garyjg/shapepuzzle/board"
+	"github.com/garyjg/shapepuzzle/shape"
+)
+
+// Puzzle is a board, with its holes already filled, and the shapes which
+// must be placed to fill the rest of it.
+type Puzzle struct {
+	Name   string
+	Board  board.Board
+	Shapes []shape.Shape
+}
+
+// Load reads the puzzle definition in the file at path.
+func Load(path string) (*Puzzle, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	p, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return p, nil
+}
+
+// Parse reads a puzzle definition.  Errors give the line number of the
+// directive at fault.
+func Parse(r io.Reader) (*Puzzle, error) {
+
+	directives, err := ReadDirectives(r, "")
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Puzzle{}
+	sized := false
+	topology := board.Plane
+	for _, d := range directives {
+		var err error
+		switch d.Args[0] {
+		case "name":
+			p.Name = strings.Join(d.Args[1:], " ")
+		case "board":
+			if sized {
+				err = fmt.Errorf("board given twice")
+			} else {
+				p.Board, err = parseBoard(d)
+				sized = true
+			}
+		case "topology":
+			if len(d.Args) != 2 {
+				err = fmt.Errorf("topology needs a name")
+			} else {
+				topology, err = board.ParseTopology(d.Args[1])
+			}
+		case "piece":
+			if len(p.Shapes) == board.MaxShapes {
+				err = fmt.Errorf("more than %d pieces", board.MaxShapes)
+				break
+			}
+			var s shape.Shape
+			s, err = parsePiece(d, len(p.Shapes)+1)
+			p.Shapes = append(p.Shapes, s)
+		default:
+			err = fmt.Errorf("unknown directive %q", d.Args[0])
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", d.Line, err)
+		}
+	}
+	if !sized {
+		return nil, fmt.Errorf("no board given")
+	}
+	if len(p.Shapes) == 0 {
+		return nil, fmt.Errorf("no pieces given")
+	}
+	p.Board = p.Board.WithTopology(topology)
+	return p, nil
+}
+
+// parseBoard reads the size of a board and places a shape with ID 0 over
+// its holes.
+func parseBoard(d *Directive) (board.Board, error) {
+
+	if len(d.Args) != 3 {
+		return board.Board{}, fmt.Errorf("board needs rows and columns")
+	}
+	nrows, err := strconv.Atoi(d.Args[1])
+	if err != nil {
+		return board.Board{}, fmt.Errorf("bad board rows: %v", err)
+	}
+	ncols, err := strconv.Atoi(d.Args[2])
+	if err != nil {
+		return board.Board{}, fmt.Errorf("bad board columns: %v", err)
+	}
+	if nrows < 1 || nrows > 8 || ncols < 1 || ncols > 8 {
+		return board.Board{}, fmt.Errorf("board is %dx%d, but must be "+
+			"from 1x1 to 8x8", nrows, ncols)
+	}
+	b := board.NewBoard(nrows, ncols)
+	if d.Grid == nil {
+		return b, nil
+	}
+	if len(d.Grid) != nrows {
+		return b, fmt.Errorf("board grid has %d rows, not %d",
+			len(d.Grid), nrows)
+	}
+	grid, err := parseGrid(d.Grid)
+	if err != nil {
+		return b, err
+	}
+	if len(grid[0]) != ncols {
+		return b, fmt.Errorf("board grid has %d columns, not %d",
+			len(grid[0]), ncols)
+	}
+	holes := shape.NewShape(0, grid)
+	if holes.Mask() != 0 {
+		b = b.Place(holes)
+	}
+	return b, nil
+}
+
+func parsePiece(d *Directive, id int) (shape.Shape, error) {
+
+	if len(d.Args) != 2 {
+		return shape.Shape{}, fmt.Errorf("piece needs a name")
+	}
+	if d.Grid == nil {
+		return shape.Shape{}, fmt.Errorf("piece %s has no grid", d.Args[1])
+	}
+	grid, err := parseGrid(d.Grid)
+	if err == nil {
+		var s shape.Shape
+		if s, err = shape.NewValidShape(id, grid); err == nil {
+			return s.WithName(d.Args[1]), nil
+		}
+	}
+	return shape.Shape{}, fmt.Errorf("piece %s: %v", d.Args[1], err)
+}
+
+// parseGrid converts rows of '#' and '.' into a grid of ones and zeros.
+func parseGrid(rows []string) ([][]int, error) {
+	for r, row := range rows {
+		if len(row) != len(rows[0]) {
+			return nil, fmt.Errorf("grid row %d has %d cells, not %d",
+				r+1, len(row), len(rows[0]))
+		}
+	}
+	return Cells(rows), nil
+}
+
+// Duplicates returns each group of pieces which are the same shape when
+// rotated or flipped over, in the order the pieces were given.  Each group
+// multiplies the number of solutions by the number of ways its pieces can
+// be swapped.
+func (p *Puzzle) Duplicates() [][]shape.Shape {
+	groups := map[shape.Key][]shape.Shape{}
+	keys := []shape.Key{}
+	for _, s := range p.Shapes {
+		key := s.CanonicalKey(shape.Free)
+		if groups[key] == nil {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], s)
+	}
+	dups := [][]shape.Shape{}
+	for _, key := range keys {
+		if len(groups[key]) > 1 {
+			dups = append(dups, groups[key])
+		}
+	}
+	return dups
+}
+
+// Write formats the puzzle in the form read by Parse.
+func (p *Puzzle) Write(w io.Writer) error {
+
+	bw := bufio.NewWriter(w)
+	if p.Name != "" {
+		fmt.Fprintf(bw, "name %s\n", p.Name)
+	}
+	b := p.Board
+	fmt.Fprintf(bw, "board %d %d\n", b.NumRows(), b.NumCols())
+	if b.Mask() != 0 {
+		bw.WriteString(b.Mask().Grid(b.NumRows(), b.NumCols()))
+	}
+	if b.Topology() != board.Plane {
+		fmt.Fprintf(bw, "topology %v\n", b.Topology())
+	}
+	for _, s := range p.Shapes {
+		name := s.Name()
+		if name == "" {
+			name = strconv.Itoa(s.ID())
+		}
+		fmt.Fprintf(bw, "piece %s\n", name)
+		bw.WriteString(s.Mask().Grid(s.NumRows(), s.NumCols()))
+	}
+	return bw.Flush()
+}
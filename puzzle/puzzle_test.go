@@ -0,0 +1,119 @@
+// -*- tab-width: 4; -*-
+
+package puzzle
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/garyjg/shapepuzzle/board"
+)
+
+const scott = `# Dana Scott's puzzle.
+name scott
+board 4 4
+....
+.##.
+.##.
+....
+piece L
+###
+#..
+piece J
+#..
+###
+piece S
+##.
+.##
+`
+
+func TestParse(t *testing.T) {
+
+	p, err := Parse(strings.NewReader(scott))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Name != "scott" || p.Board.NumRows() != 4 || len(p.Shapes) != 3 {
+		t.Errorf("parsed %q %dx%d with %d pieces", p.Name, p.Board.NumRows(),
+			p.Board.NumCols(), len(p.Shapes))
+	}
+	if p.Board.Mask() != 0x0060600000000000 {
+		t.Errorf("board holes %v", p.Board.Mask())
+	}
+	if p.Shapes[1].ID() != 2 || p.Shapes[1].Name() != "J" {
+		t.Errorf("second piece is #%d %q", p.Shapes[1].ID(),
+			p.Shapes[1].Name())
+	}
+
+	var buf bytes.Buffer
+	if err := p.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	again, err := Parse(&buf)
+	if err != nil {
+		t.Fatalf("%v:\n%s", err, buf.String())
+	}
+	if again.Board.Mask() != p.Board.Mask() || len(again.Shapes) != 3 ||
+		again.Shapes[2].Key() != p.Shapes[2].Key() {
+		t.Errorf("written puzzle reads back differently:\n%s", buf.String())
+	}
+}
+
+func TestTopology(t *testing.T) {
+
+	p, err := Parse(strings.NewReader("topology torus\n" + scott))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := p.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	again, err := Parse(&buf)
+	if err != nil {
+		t.Fatalf("%v:\n%s", err, buf.String())
+	}
+	if again.Board.Topology() != board.Torus ||
+		again.Board.Mask() != p.Board.Mask() {
+		t.Errorf("written puzzle reads back as a %v:\n%s",
+			again.Board.Topology(), buf.String())
+	}
+}
+
+func TestDuplicates(t *testing.T) {
+
+	p, err := Parse(strings.NewReader(scott))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dups := p.Duplicates()
+	if len(dups) != 1 || len(dups[0]) != 2 || dups[0][0].Name() != "L" ||
+		dups[0][1].Name() != "J" {
+		t.Errorf("Duplicates() = %v", dups)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := map[string]string{
+		"board 4\npiece A\n#\n":          "line 1: board needs",
+		"board 9 9\npiece A\n#\n":        "line 1: board is 9x9",
+		"board 2 2\n...\n..\npiece A\n#": "line 1: grid row 2",
+		"board 2 2\n":                    "no pieces",
+		"piece A\n#\n":                   "no board",
+		"board 2 2\ntopology klein\n":    "line 2: unknown board topology",
+		"board 2 2\npiece A\n":           "line 2: piece A has no grid",
+		"board 2 2\nshape A\n#\n":        "line 2: unknown directive",
+		"##\nboard 2 2\n":                "line 1: grid without",
+		"board 2 2\npiece A\n#.\n.#\n":   "line 2: piece A: shape #1: grid is not 4-connected",
+		"board 2 2\npiece A\n..\n":       "line 2: piece A: shape #1: grid has no cells",
+		"board 8 8\n" + strings.Repeat("piece A\n#\n", 65): "line 130: " +
+			"more than 64 pieces",
+	}
+	for text, want := range tests {
+		_, err := Parse(strings.NewReader(text))
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Parse(%q) error %v, want %q", text, err, want)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+// -*- tab-width: 4; -*-
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/garyjg/shapepuzzle/board"
+)
+
+// rate prints the difficulty rating of a puzzle and its breakdown.
+func rate(args []string) int {
+
+	fs := flag.NewFlagSet("rate", flag.ContinueOnError)
+	format := fs.String("format", "table", "print the rating as a table or json")
+	logLevel := fs.String("log-level", "warn",
+		"log messages at this level or above: debug, info, warn or error")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: shapepuzzle rate [flags] NAME|FILE")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 || (*format != "table" && *format != "json") {
+		fs.Usage()
+		return 2
+	}
+	logger, err := newLogger(*logLevel)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	p, err := loadPuzzle(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	r, err := p.Board.Rate(p.Shapes, board.Options{Logger: logger})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if *format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(r)
+		return 0
+	}
+	r.WriteTable(os.Stdout)
+	return 0
+}
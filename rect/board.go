@@ -0,0 +1,188 @@
+// -*- tab-width: 4; -*-
+
+// Package rect solves polyomino puzzles on rectangles too big for the 8x8
+// mask of the board package, such as the pentomino rectangles, with the
+// search of the cover package.
+//
+// Cells are numbered row by row, the cell at row R and column C being
+// cell R*cols+C of a cover.Set, so a board may have any shape of up to 128
+// cells.  The pieces are the Shapes of the shape package, turned by its
+// transforms.
+package rect
+
+import (
+	"fmt"
+
+	"github.com/garyjg/shapepuzzle/cover"
+	"github.com/garyjg/shapepuzzle/shape"
+)
+
+// MaxCells is the largest number of cells on a board, as many as the cover
+// search can fill.
+const MaxCells = cover.MaxCells
+
+// Placement is a shape at one position on a board, given by the shape's ID
+// and the set of the cells it covers.
+type Placement = cover.Placement[cover.Set]
+
+type filling = cover.Filling[cover.Set]
+
+// Board is a rectangle of square cells with the shape placements made on
+// it so far.  Copies of a Board share the neighbors of its cells.
+type Board struct {
+	filling
+	rows, cols int
+	neighbors  []cover.Set
+}
+
+// NewBoard makes an empty board of the given size.  It returns an error if
+// it has more than MaxCells cells.
+func NewBoard(rows int, cols int) (Board, error) {
+	if rows < 1 || cols < 1 || rows*cols > MaxCells {
+		return Board{}, fmt.Errorf("board is %dx%d, but must have from 1 "+
+			"to %d cells", rows, cols, MaxCells)
+	}
+	b := Board{rows: rows, cols: cols}
+	b.neighbors = make([]cover.Set, rows*cols)
+	for r := 0; r < rows; r++ {
+		for c := 0; c < cols; c++ {
+			b.neighbors[r*cols+c] = b.Bit(r-1, c).Or(b.Bit(r+1, c)).
+				Or(b.Bit(r, c-1)).Or(b.Bit(r, c+1))
+		}
+	}
+	return b, nil
+}
+
+// NumRows returns the number of rows on the board.
+func (b Board) NumRows() int {
+	return b.rows
+}
+
+// NumCols returns the number of columns on the board.
+func (b Board) NumCols() int {
+	return b.cols
+}
+
+// Bit returns the Set of the cell at row r and column c, which is empty if
+// the cell is not on the board.
+func (b Board) Bit(r int, c int) cover.Set {
+	if r < 0 || r >= b.rows || c < 0 || c >= b.cols {
+		return cover.Set{}
+	}
+	return cover.Cell(r*b.cols + c)
+}
+
+// Region returns the Set of every cell of the board.
+func (b Board) Region() cover.Set {
+	return cover.FirstCells(b.rows * b.cols)
+}
+
+// Place returns a copy of the Board with the placement added.  It does
+// not check that the placement fits.
+func (b Board) Place(p Placement) Board {
+	b.filling = b.filling.Place(p)
+	return b
+}
+
+// Neighbors returns the cells of the board which share an edge with any
+// cell of m and are not in m themselves.
+func (b Board) Neighbors(m cover.Set) cover.Set {
+	n := cover.Set{}
+	for rest := m; !rest.Empty(); {
+		i := rest.First()
+		rest = rest.AndNot(cover.Cell(i))
+		n = n.Or(b.neighbors[i])
+	}
+	return n.AndNot(m)
+}
+
+// orientations returns the distinct orientations of the Shape under the
+// four rotations, and the four reflections as well if mirror is true.
+func orientations(s shape.Shape, mirror bool) []shape.Shape {
+	if mirror {
+		return s.Permutations()
+	}
+	shapes := []shape.Shape{}
+	seen := map[shape.Key]bool{}
+	for t := shape.Identity; t <= shape.Rotate270; t++ {
+		o := s.Transform(t)
+		if key := o.Key(); !seen[key] {
+			seen[key] = true
+			shapes = append(shapes, o)
+		}
+	}
+	return shapes
+}
+
+// placementsOf returns every orientation of the Shape at every position on
+// the Board which does not overlap the cells already filled.
+func (b Board) placementsOf(s shape.Shape, mirror bool) []cover.Set {
+	places := []cover.Set{}
+	for _, o := range orientations(s, mirror) {
+		for r := 0; r+o.NumRows() <= b.rows; r++ {
+			for c := 0; c+o.NumCols() <= b.cols; c++ {
+				m := cover.Set{}
+				for dr, dc := range o.Mask().Cells() {
+					m = m.Or(b.Bit(r+dr, c+dc))
+				}
+				if m.And(b.Mask()).Empty() {
+					places = append(places, m)
+				}
+			}
+		}
+	}
+	return places
+}
+
+// Channel is a channel for passing Board states.
+type Channel chan Board
+
+// Solve searches for every way to fill the rest of the board with one
+// placement of each shape, and sends each solution on the channel it
+// returns, which is closed when the search is done.  Shapes are turned by
+// the four rotations, and flipped over as well if mirror is true.  It
+// returns an error, without searching, if there are more shapes than the
+// search can place.
+func (b Board) Solve(shapes []shape.Shape, mirror bool) (Channel, error) {
+
+	p := &cover.Problem{Region: b.Region().AndNot(b.Mask()),
+		Neighbors: b.Neighbors}
+	for _, s := range shapes {
+		p.Pieces = append(p.Pieces, b.placementsOf(s, mirror))
+	}
+	bc, err := cover.Stream(p, func(choices []cover.Choice) Board {
+		nb := b
+		for _, ch := range choices {
+			nb = nb.Place(Placement{ID: shapes[ch.Piece].ID(), Mask: ch.Cells})
+		}
+		return nb
+	})
+	return bc, err
+}
+
+// Grid returns the ID of the shape in each cell of the board, or 0 where
+// the cell is empty.
+func (b Board) Grid() [][]int {
+	grid := make([][]int, b.rows)
+	for r := range grid {
+		grid[r] = make([]int, b.cols)
+		for c := range grid[r] {
+			grid[r][c] = b.ID(b.Bit(r, c))
+		}
+	}
+	return grid
+}
+
+// String draws the Board a row at a time, with the ID of the shape in each
+// cell, like the board package draws a Board.
+func (b Board) String() string {
+	buf := ""
+	for _, row := range b.Grid() {
+		buf += "["
+		for _, id := range row {
+			buf += fmt.Sprintf(" %2d", id)
+		}
+		buf += "]\n"
+	}
+	return buf
+}
@@ -0,0 +1,86 @@
+// -*- tab-width: 4; -*-
+
+package rect
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/garyjg/shapepuzzle/puzzle"
+	"github.com/garyjg/shapepuzzle/shape"
+)
+
+// Puzzle is a rectangle and the polyominoes which must fill it.  Pieces
+// may be flipped over unless OneSided is true.
+//
+// A rect puzzle file has the directives of the puzzle package, with the
+// board given as "board ROWS COLS" but without a grid of holes, since the
+// board may be bigger than 8x8:
+//
+//	# The twelve pentominoes on a 6x10 rectangle.
+//	name 6x10
+//	board 6 10
+//	piece F
+//	.##
+//	##.
+//	.#.
+//
+// A one-sided directive stops pieces from being flipped over.
+type Puzzle struct {
+	Name     string
+	Board    Board
+	Shapes   []shape.Shape
+	OneSided bool
+}
+
+// Load reads the rect puzzle definition in the file at path.
+func Load(path string) (*Puzzle, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	p, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return p, nil
+}
+
+// grammar describes rect puzzle files to puzzle.ParseDefinition.
+var grammar = puzzle.Grammar[Board, shape.Shape]{
+	Flags: []string{"one-sided"},
+	Board: parseBoard,
+	Piece: func(id int, grid []string) (shape.Shape, error) {
+		return shape.NewValidShape(id, puzzle.Cells(grid))
+	},
+	Name: shape.Shape.WithName,
+}
+
+// Parse reads a rect puzzle definition.  Errors give the line number of
+// the directive at fault.
+func Parse(r io.Reader) (*Puzzle, error) {
+	d, err := puzzle.ParseDefinition(r, grammar)
+	if err != nil {
+		return nil, err
+	}
+	return &Puzzle{Name: d.Name, Board: d.Board, Shapes: d.Shapes,
+		OneSided: d.Flags["one-sided"]}, nil
+}
+
+func parseBoard(args []string) (Board, error) {
+	if len(args) != 2 {
+		return Board{}, fmt.Errorf("board needs rows and columns")
+	}
+	size := [2]int{}
+	for i, arg := range args {
+		n, err := strconv.Atoi(arg)
+		if err != nil {
+			return Board{}, fmt.Errorf("bad board size: %v", err)
+		}
+		size[i] = n
+	}
+	return NewBoard(size[0], size[1])
+}
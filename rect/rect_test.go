@@ -0,0 +1,100 @@
+// -*- tab-width: 4; -*-
+
+package rect
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/garyjg/shapepuzzle/cover"
+	"github.com/garyjg/shapepuzzle/shape"
+)
+
+func TestBoards(t *testing.T) {
+	b, err := NewBoard(6, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := b.Region().Count(); n != 60 {
+		t.Errorf("6x10 board has %d cells", n)
+	}
+	if n := b.Neighbors(b.Bit(0, 0)).Count(); n != 2 {
+		t.Errorf("corner has %d neighbors, expected 2", n)
+	}
+	if n := b.Neighbors(b.Bit(2, 3).Or(b.Bit(2, 4))).Count(); n != 6 {
+		t.Errorf("domino has %d neighbors, expected 6", n)
+	}
+	if !b.Bit(6, 0).Empty() || !b.Bit(0, -1).Empty() {
+		t.Errorf("cells off the board should be empty")
+	}
+	if _, err := NewBoard(12, 11); err == nil {
+		t.Errorf("board of 132 cells should be an error")
+	}
+}
+
+func TestSolve(t *testing.T) {
+	// Fill all of a 10x10 board but a 2x3 rectangle in its lower right
+	// corner, which three dominoes fill in three ways, each in six orders.
+	b, err := NewBoard(10, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hole := cover.Set{}
+	for r := 8; r < 10; r++ {
+		for c := 7; c < 10; c++ {
+			hole = hole.Or(b.Bit(r, c))
+		}
+	}
+	b = b.Place(Placement{ID: 9, Mask: b.Region().AndNot(hole)})
+	dominoes := shape.MakeShapes([][][]int{{{1, 1}}, {{1, 1}}, {{1, 1}}})
+	bc, err := b.Solve(dominoes, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	for sol := range bc {
+		if sol.Mask() != sol.Region() || len(sol.Placements()) != 4 {
+			t.Errorf("solution does not fill the board:\n%v", sol)
+		}
+		n++
+	}
+	if n != 18 {
+		t.Errorf("found %d solutions, expected 18", n)
+	}
+}
+
+func TestString(t *testing.T) {
+	b, err := NewBoard(2, 9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b = b.Place(Placement{ID: 3, Mask: b.Bit(0, 8).Or(b.Bit(1, 8))})
+	expect := "[  0  0  0  0  0  0  0  0  3]\n[  0  0  0  0  0  0  0  0  3]\n"
+	if got := b.String(); got != expect {
+		t.Errorf("got board\n%s, expected\n%s", got, expect)
+	}
+}
+
+func TestParse(t *testing.T) {
+	p, err := Parse(strings.NewReader("name bar\nboard 1 10\none-sided\n" +
+		"piece A\n#####\npiece B\n#####\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Name != "bar" || p.Board.NumCols() != 10 || len(p.Shapes) != 2 ||
+		!p.OneSided || p.Shapes[1].Name() != "B" {
+		t.Errorf("parsed %+v", p)
+	}
+	tests := []string{
+		"piece A\n#\n",
+		"board 12 12\npiece A\n#\n",
+		"board 6\npiece A\n#\n",
+		"board 6 10\n",
+		"board 6 10\npiece A\n#.\n.#\n",
+	}
+	for _, test := range tests {
+		if _, err := Parse(strings.NewReader(test)); err == nil {
+			t.Errorf("parsing %q should fail", test)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+// -*- tab-width: 4; -*-
+
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/garyjg/shapepuzzle/catalog"
+)
+
+// cellFlags are the flags of the cell search, which a Rect puzzle is not
+// solved with.
+var cellFlags = []string{"strategy", "workers", "checkpoint",
+	"checkpoint-interval", "resume", "shard", "optional", "stats",
+	"placements"}
+
+// solveRect solves a catalog puzzle too big for the board package with the
+// cover search of the rect package, and prints every solution.  The cell
+// search options do not apply to it, so it is a usage error to set any of
+// cellFlags.
+func solveRect(entry catalog.Entry, set map[string]bool,
+	logger *slog.Logger) int {
+
+	for _, name := range cellFlags {
+		if set[name] {
+			fmt.Fprintf(os.Stderr, "-%s does not apply to %s, which is "+
+				"solved by the rect package\n", name, entry.Name)
+			return 2
+		}
+	}
+	p, err := entry.RectPuzzle()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Printf("Initial board:\n%v", p.Board)
+	bc, err := p.Board.Solve(p.Shapes, !p.OneSided)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	nfound := 0
+	for b := range bc {
+		fmt.Printf("Solution found.\n%s\n", b)
+		nfound++
+	}
+	printCount(nfound)
+	if nfound != entry.Solutions {
+		logger.Warn("solution count differs from the catalog",
+			"puzzle", entry.Name, "found", nfound, "known", entry.Solutions)
+	}
+	return 0
+}
@@ -0,0 +1,73 @@
+// -*- tab-width: 4; -*-
+
+package shape
+
+import (
+	"fmt"
+
+	"github.com/garyjg/shapepuzzle/mask"
+)
+
+// Key identifies the grid of a Shape in one orientation: two shapes have
+// the same Key exactly when Equals is true for them.  Keys are comparable,
+// so they can be used as map keys.
+type Key struct {
+	Rows uint8
+	Cols uint8
+	Mask mask.Bits
+	Gaps mask.Bits
+}
+
+// Less orders Keys by their masks first, so the smallest Key of a shape
+// has its cells packed towards the upper left corner.
+func (k Key) Less(b Key) bool {
+	if k.Mask != b.Mask {
+		return k.Mask < b.Mask
+	}
+	if k.Gaps != b.Gaps {
+		return k.Gaps < b.Gaps
+	}
+	if k.Rows != b.Rows {
+		return k.Rows < b.Rows
+	}
+	return k.Cols < b.Cols
+}
+
+func (k Key) String() string {
+	return fmt.Sprintf("%dx%d:%v:%v", k.Rows, k.Cols, k.Mask, k.Gaps)
+}
+
+// Key returns the Key of the Shape's grid, as it would be placed at the
+// upper left corner of a board.
+func (s Shape) Key() Key {
+	m, gaps := mask.ComputeMask(s.shape)
+	return Key{uint8(s.NumRows()), uint8(s.NumCols()), m, gaps}
+}
+
+// Canonical returns the orientation of the Shape with the smallest Key
+// among the transforms allowed by kind, so every orientation of a shape
+// has the same canonical form.
+func (s Shape) Canonical(kind Kind) Shape {
+	best := s
+	key := s.Key()
+	for i := 0; i < 8 && kind != Fixed; i++ {
+		if i == 4 {
+			if kind == OneSided {
+				break
+			}
+			s = s.flip()
+		}
+		if k := s.Key(); k.Less(key) {
+			best, key = s, k
+		}
+		s = s.rotate()
+	}
+	return best
+}
+
+// CanonicalKey returns the Key of the canonical form of the Shape, which
+// is the same for any two shapes which match under the transforms allowed
+// by kind.
+func (s Shape) CanonicalKey(kind Kind) Key {
+	return s.Canonical(kind).Key()
+}
@@ -0,0 +1,209 @@
+// -*- tab-width: 4; -*-
+
+package shape
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/garyjg/shapepuzzle/mask"
+)
+
+// MaxPolyomino is the largest number of cells Polyominoes can generate,
+// since the longest polyomino of n cells is n cells long, and a mask only
+// holds 8 cells in a row.
+const MaxPolyomino = 8
+
+// Kind selects which transforms make two polyominoes the same.
+type Kind int
+
+const (
+	// Free polyominoes are the same if one can be rotated or flipped over
+	// to match the other, so each one stands for a piece which can be
+	// placed any way up.
+	Free Kind = iota
+	// OneSided polyominoes are the same only if one can be rotated to
+	// match the other, as for pieces which cannot be flipped over.
+	OneSided
+	// Fixed polyominoes are the same only if they match without being
+	// rotated or flipped, so every orientation is a separate shape.
+	Fixed
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Free:
+		return "free"
+	case OneSided:
+		return "one-sided"
+	case Fixed:
+		return "fixed"
+	}
+	return fmt.Sprintf("Kind(%d)", int(k))
+}
+
+// polyominoNames gives the usual names of the free tetrominoes and
+// pentominoes, each in the orientation Polyominoes returns it.
+var polyominoNames = map[string][][]int{
+	"I4": {{1, 1, 1, 1}},
+	"L4": {{1, 0}, {1, 0}, {1, 1}},
+	"O4": {{1, 1}, {1, 1}},
+	"S4": {{0, 1, 1}, {1, 1, 0}},
+	"T4": {{1, 1, 1}, {0, 1, 0}},
+
+	"F": {{0, 1, 1}, {1, 1, 0}, {0, 1, 0}},
+	"I": {{1}, {1}, {1}, {1}, {1}},
+	"L": {{1, 0}, {1, 0}, {1, 0}, {1, 1}},
+	"N": {{0, 1}, {1, 1}, {1, 0}, {1, 0}},
+	"P": {{1, 1}, {1, 1}, {1, 0}},
+	"T": {{1, 1, 1}, {0, 1, 0}, {0, 1, 0}},
+	"U": {{1, 0, 1}, {1, 1, 1}},
+	"V": {{1, 0, 0}, {1, 0, 0}, {1, 1, 1}},
+	"W": {{1, 0, 0}, {1, 1, 0}, {0, 1, 1}},
+	"X": {{0, 1, 0}, {1, 1, 1}, {0, 1, 0}},
+	"Y": {{0, 1}, {1, 1}, {0, 1}, {0, 1}},
+	"Z": {{1, 1, 0}, {0, 1, 0}, {0, 1, 1}},
+}
+
+// Polyominoes generates every polyomino of n cells, from 1 up to
+// MaxPolyomino, with the given kind of transforms treated as the same
+// shape.  The polyominoes are grown one cell at a time from the monomino.
+//
+// The free pentominoes are named F, I, L, N, P, T, U, V, W, X, Y and Z and
+// the free tetrominoes I, L, O, S and T, with the number of cells appended
+// to the tetromino names so that they differ from the pentominoes.  Other
+// sizes have no names, and are ordered by their canonical masks.  IDs
+// count from 1 in the order returned, so they are the same every time.
+//
+// One-sided shapes follow the free shape they come from, with the mirror
+// image, if it differs, named with a trailing prime, as in F'.  Fixed
+// shapes list every orientation of each free shape as given by
+// Permutations, named with the orientation number, as in F:1 to F:8.
+func Polyominoes(n int, kind Kind) ([]Shape, error) {
+
+	if n < 1 || n > MaxPolyomino {
+		return nil, fmt.Errorf("polyominoes need 1 to %d cells, not %d",
+			MaxPolyomino, n)
+	}
+	if kind != Free && kind != OneSided && kind != Fixed {
+		return nil, fmt.Errorf("unknown polyomino kind: %v", kind)
+	}
+
+	names := map[Key]string{}
+	for name, grid := range polyominoNames {
+		names[NewShape(0, grid).CanonicalKey(Free)] = name
+	}
+
+	// Group the fixed polyominoes by their free shape, keeping the named
+	// orientation of each free shape where there is one.
+	free := map[Key]Shape{}
+	for _, m := range growPolyominoes(n) {
+		s := NewShape(0, maskGrid(m)).Canonical(Free)
+		key := s.Key()
+		if _, found := free[key]; found {
+			continue
+		}
+		if name, ok := names[key]; ok {
+			s = NewShape(0, polyominoNames[name])
+			s.name = name
+		}
+		free[key] = s
+	}
+	keys := make([]Key, 0, len(free))
+	for key := range free {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := free[keys[i]], free[keys[j]]
+		if a.name != b.name {
+			return a.name < b.name
+		}
+		return keys[i].Less(keys[j])
+	})
+
+	shapes := []Shape{}
+	add := func(s Shape, name string) {
+		s.id = len(shapes) + 1
+		s.name = name
+		shapes = append(shapes, s)
+	}
+	for _, key := range keys {
+		s := free[key]
+		switch kind {
+		case Free:
+			add(s, s.name)
+		case OneSided:
+			add(s, s.name)
+			mirror := s.flip()
+			if mirror.CanonicalKey(OneSided) != s.CanonicalKey(OneSided) {
+				add(mirror, primed(s.name))
+			}
+		case Fixed:
+			for i, p := range s.Permutations() {
+				add(p, numbered(s.name, i+1))
+			}
+		}
+	}
+	return shapes, nil
+}
+
+func primed(name string) string {
+	if name == "" {
+		return ""
+	}
+	return name + "'"
+}
+
+func numbered(name string, i int) string {
+	if name == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d", name, i)
+}
+
+// growPolyominoes returns the masks of every fixed polyomino with n cells,
+// each one moved to the upper left corner.  Every polyomino of n cells is
+// a polyomino of n-1 cells with one neighbouring cell added.
+func growPolyominoes(n int) []mask.Bits {
+
+	const col0 = mask.Bits(0x8080808080808080)
+	const col7 = mask.Bits(0x0101010101010101)
+
+	fixed := []mask.Bits{mask.FirstBit()}
+	for size := 1; size < n; size++ {
+		seen := map[mask.Bits]bool{}
+		next := []mask.Bits{}
+		for _, m := range fixed {
+			// Leave room to grow up and to the left.  Cells shifted off
+			// the right or bottom edges are lost, but those polyominoes
+			// can also be grown from the other end.
+			m = m.Translate(1, 1)
+			around := (m<<8 | m>>8 | (m<<1)&^col7 | (m>>1)&^col0) &^ m
+			for around != 0 {
+				cell := around & -around
+				around &^= cell
+				grown := (m | cell).Normalize()
+				if !seen[grown] {
+					seen[grown] = true
+					next = append(next, grown)
+				}
+			}
+		}
+		fixed = next
+	}
+	return fixed
+}
+
+// maskGrid converts a mask into the smallest grid which holds its cells.
+func maskGrid(m mask.Bits) [][]int {
+	m = m.Normalize()
+	_, _, nrow, ncol := m.BoundingBox()
+	grid := make([][]int, nrow)
+	for r := range grid {
+		grid[r] = make([]int, ncol)
+	}
+	for r, c := range m.Cells() {
+		grid[r][c] = 1
+	}
+	return grid
+}
@@ -12,6 +12,7 @@ import (
 // of a grid, and a row
 type Shape struct {
 	id    int
+	name  string
 	shape [][]int
 	mask  mask.Bits
 	gaps  mask.Bits
@@ -23,7 +24,7 @@ type Shape struct {
 // grid position at the upper left (0, 0), then the masks are updated from
 // the current position.
 func NewShape(id int, grid [][]int) Shape {
-	s := Shape{id, grid, 0, 0, 0, 0}
+	s := Shape{id, "", grid, 0, 0, 0, 0}
 	(&s).ComputeMask()
 	return s
 }
@@ -50,6 +51,14 @@ func MakeShapes(grids [][][]int) []Shape {
 	return shapes
 }
 
+// FromMask makes a Shape with the given id whose cells are the cells set
+// in the mask, in the same position, with the smallest grid which holds
+// them.  It is the reverse of Mask for shapes without gaps.
+func FromMask(id int, m mask.Bits) Shape {
+	row, col, _, _ := m.BoundingBox()
+	return NewShape(id, maskGrid(m)).Translate(row, col)
+}
+
 // NumRows returns the number of rows in a shape, equivalent to the length
 // of the first grid dimension.
 func (s Shape) NumRows() int {
@@ -67,6 +76,18 @@ func (s Shape) ID() int {
 	return s.id
 }
 
+// WithName returns a copy of the Shape with the given name.
+func (s Shape) WithName(name string) Shape {
+	s.name = name
+	return s
+}
+
+// Name returns the well-known name of the Shape, such as "F" for one of
+// the pentominoes from Polyominoes, or an empty string if it has none.
+func (s Shape) Name() string {
+	return s.name
+}
+
 // String formats a shape into text, one line for each row, and each column
 // represented as a string of 0 and 1.
 func (s Shape) String() string {
@@ -129,7 +150,9 @@ func (s Shape) rotate() Shape {
 			grid[r][c] = s.shape[ncol-c-1][r]
 		}
 	}
-	return NewShape(s.id, grid)
+	rotated := NewShape(s.id, grid)
+	rotated.name = s.name
+	return rotated
 }
 
 // Equals compares the Shape with the Shape b and returns true if the sizes
@@ -158,28 +181,22 @@ func (s Shape) flip() Shape {
 			grid[r][c] = s.shape[nrow-r-1][c]
 		}
 	}
-	return NewShape(s.id, grid)
-}
-
-func searchShapes(shapes []Shape, pred func(s Shape) bool) (bool, int) {
-	for i, s := range shapes {
-		if pred(s) {
-			return true, i
-		}
-	}
-	return false, 0
+	flipped := NewShape(s.id, grid)
+	flipped.name = s.name
+	return flipped
 }
 
 // Permutations returns all distinct Shapes generated from rotating Shape
 // and flipping Shape all possible ways.
 func (s Shape) Permutations() []Shape {
 	shapes := []Shape{}
+	seen := map[Key]bool{}
 	for i := 0; i < 8; i++ {
 		if i == 4 {
 			s = s.flip()
 		}
-		found, _ := searchShapes(shapes, func(b Shape) bool { return b.Equals(s) })
-		if !found {
+		if key := s.Key(); !seen[key] {
+			seen[key] = true
 			shapes = append(shapes, s)
 		}
 		s = s.rotate()
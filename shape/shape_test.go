@@ -4,6 +4,7 @@ package shape
 
 import (
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/garyjg/shapepuzzle/mask"
@@ -489,32 +490,6 @@ func TestShape_flip(t *testing.T) {
 	}
 }
 
-func Test_searchShapes(t *testing.T) {
-	type args struct {
-		shapes []Shape
-		pred   func(s Shape) bool
-	}
-	tests := []struct {
-		name  string
-		args  args
-		want  bool
-		want1 int
-	}{
-		// TODO: Add test cases.
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			got, got1 := searchShapes(tt.args.shapes, tt.args.pred)
-			if got != tt.want {
-				t.Errorf("searchShapes() got = %v, want %v", got, tt.want)
-			}
-			if got1 != tt.want1 {
-				t.Errorf("searchShapes() got1 = %v, want %v", got1, tt.want1)
-			}
-		})
-	}
-}
-
 func TestShape_Permutations(t *testing.T) {
 
 	cross := NewShape(1, [][]int{{0, 1, 0}, {1, 1, 1}, {0, 1, 0}})
@@ -538,3 +513,163 @@ func TestShape_Permutations(t *testing.T) {
 		})
 	}
 }
+
+func TestPolyominoes(t *testing.T) {
+	counts := map[Kind][]int{
+		Free:     {1, 1, 2, 5, 12, 35, 108, 369},
+		OneSided: {1, 1, 2, 7, 18, 60, 196, 704},
+		Fixed:    {1, 2, 6, 19, 63, 216, 760, 2725},
+	}
+	for kind, want := range counts {
+		for n := 1; n <= MaxPolyomino; n++ {
+			shapes, err := Polyominoes(n, kind)
+			if err != nil {
+				t.Fatalf("Polyominoes(%d, %v): %v", n, kind, err)
+			}
+			if len(shapes) != want[n-1] {
+				t.Errorf("%d %v polyominoes of %d cells, want %d",
+					len(shapes), kind, n, want[n-1])
+			}
+			for i, s := range shapes {
+				if s.ID() != i+1 {
+					t.Errorf("%v polyomino %d has id %d", kind, i, s.ID())
+				}
+			}
+		}
+	}
+
+	names := ""
+	pentominoes, _ := Polyominoes(5, Free)
+	for _, s := range pentominoes {
+		names += s.Name()
+	}
+	if names != "FILNPTUVWXYZ" {
+		t.Errorf("pentomino names %q", names)
+	}
+	onesided, _ := Polyominoes(5, OneSided)
+	if onesided[1].Name() != "F'" || onesided[2].Name() != "I" {
+		t.Errorf("one-sided pentominoes start %q, %q, %q", onesided[0].Name(),
+			onesided[1].Name(), onesided[2].Name())
+	}
+
+	if _, err := Polyominoes(0, Free); err == nil {
+		t.Errorf("Polyominoes should reject 0 cells")
+	}
+	if _, err := Polyominoes(MaxPolyomino+1, Free); err == nil {
+		t.Errorf("Polyominoes should reject %d cells", MaxPolyomino+1)
+	}
+}
+
+func TestCanonical(t *testing.T) {
+
+	f := NewShape(1, [][]int{{0, 1, 1}, {1, 1, 0}, {0, 1, 0}})
+	mirror := f.flip()
+	for _, p := range f.Permutations() {
+		if p.CanonicalKey(Free) != f.CanonicalKey(Free) {
+			t.Errorf("free canonical keys differ: %v and %v",
+				p.CanonicalKey(Free), f.CanonicalKey(Free))
+		}
+	}
+	if f.rotate().CanonicalKey(OneSided) != f.CanonicalKey(OneSided) {
+		t.Errorf("rotated shape has a different one-sided key")
+	}
+	if mirror.CanonicalKey(OneSided) == f.CanonicalKey(OneSided) {
+		t.Errorf("mirror image has the same one-sided key")
+	}
+	if f.rotate().CanonicalKey(Fixed) == f.CanonicalKey(Fixed) {
+		t.Errorf("rotated shape has the same fixed key")
+	}
+	if !f.Canonical(Free).Equals(mirror.Canonical(Free)) {
+		t.Errorf("canonical forms differ:\n%v%v", f.Canonical(Free),
+			mirror.Canonical(Free))
+	}
+
+	// Gaps and blank edges are part of the key.
+	gap := NewShape(2, [][]int{{1, 1}, {1, 2}})
+	full := NewShape(3, [][]int{{1, 1}, {1, 1}})
+	blank := NewShape(4, [][]int{{1, 1, 0}, {1, 1, 0}})
+	if gap.Key() == full.Key() || blank.Key() == full.Key() {
+		t.Errorf("keys should differ: %v %v %v", gap.Key(), full.Key(),
+			blank.Key())
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		grid [][]int
+		want string
+	}{
+		{[][]int{{1, 1}, {0, 1}}, ""},
+		{[][]int{{0, 1, 0}, {1, 2, 1}, {0, 1, 0}}, ""},
+		{[][]int{}, "grid is empty"},
+		{[][]int{{1, 1}, {1}}, "row 2 has 1 cells, not 2"},
+		{[][]int{{0, 0}, {0, 0}}, "grid has no cells"},
+		{[][]int{{1, 3}}, "cell (0, 1) is 3"},
+		{[][]int{{1, 0}, {0, 1}}, "not 4-connected"},
+		{[][]int{{1, 1, 1, 1, 1, 1, 1, 1, 1}}, "larger than 8x8"},
+	}
+	for _, tt := range tests {
+		_, err := NewValidShape(7, tt.grid)
+		if tt.want == "" && err != nil {
+			t.Errorf("NewValidShape(%v) error: %v", tt.grid, err)
+		}
+		if tt.want != "" && (err == nil ||
+			!strings.Contains(err.Error(), tt.want)) {
+			t.Errorf("NewValidShape(%v) error %v, want %q", tt.grid, err,
+				tt.want)
+		}
+	}
+
+	diagonal := NewShape(1, [][]int{{1, 0}, {0, 1}})
+	if err := diagonal.ValidateConnectivity(Connect8); err != nil {
+		t.Errorf("diagonal shape should be 8-connected: %v", err)
+	}
+	if err := diagonal.Validate(); err == nil ||
+		!strings.HasPrefix(err.Error(), "shape #1: ") {
+		t.Errorf("diagonal shape Validate() = %v", err)
+	}
+}
+
+func TestSymmetry(t *testing.T) {
+	tests := []struct {
+		grid  [][]int
+		name  string
+		order int
+	}{
+		{[][]int{{0, 1, 0}, {1, 1, 1}, {0, 1, 0}}, "D4", 8},
+		{[][]int{{0, 1, 1}, {1, 1, 0}, {0, 1, 0}}, "C1", 1},
+		{[][]int{{1}, {1}, {1}, {1}, {1}}, "D2 orthogonal", 4},
+		{[][]int{{1, 1, 0}, {1, 1, 1}, {0, 1, 1}}, "D2 diagonal", 4},
+		{[][]int{{1, 1, 0}, {0, 1, 0}, {0, 1, 1}}, "C2", 2},
+		{[][]int{{1, 1, 1}, {0, 1, 0}, {0, 1, 0}}, "D1 vertical", 2},
+		{[][]int{{1, 0, 1}, {1, 1, 1}}, "D1 vertical", 2},
+		{[][]int{{1, 1}, {1, 0}, {1, 1}}, "D1 horizontal", 2},
+		{[][]int{{1, 0, 0}, {1, 1, 0}, {0, 1, 1}}, "D1 antidiagonal", 2},
+		{[][]int{{1, 1, 1}, {1, 0, 0}, {1, 0, 0}}, "D1 diagonal", 2},
+		{[][]int{{0, 1, 0, 0}, {0, 1, 1, 1}, {1, 1, 1, 0}, {0, 0, 1, 0}},
+			"C4", 4},
+	}
+	for _, tt := range tests {
+		s := NewShape(1, tt.grid)
+		sym := s.Symmetry()
+		if sym.Name() != tt.name || sym.Order() != tt.order {
+			t.Errorf("%v symmetry %s of order %d, want %s of order %d",
+				tt.grid, sym.Name(), sym.Order(), tt.name, tt.order)
+		}
+		if n := len(s.Permutations()); n*sym.Order() != 8 {
+			t.Errorf("%v has %d permutations with symmetry %v", tt.grid, n,
+				sym)
+		}
+	}
+
+	s := NewShape(1, [][]int{{1, 1, 1}, {1, 0, 0}})
+	for i, p := range s.Permutations() {
+		if !p.Equals(s.Transform(Transform(i))) {
+			t.Errorf("permutation %d is not %v", i, Transform(i))
+		}
+	}
+	if !s.Transform(ReflectDiagonal).Equals(
+		NewShape(1, [][]int{{1, 1}, {1, 0}, {1, 0}})) {
+		t.Errorf("diagonal reflection is not the transpose")
+	}
+}
@@ -0,0 +1,155 @@
+// -*- tab-width: 4; -*-
+
+package shape
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Transform is one of the eight symmetries of the square, the dihedral
+// group D4.  The transforms are numbered in the order Permutations tries
+// them, so the orientations it returns are the transforms of the Shape
+// with repeats left out.
+type Transform int
+
+const (
+	// Identity leaves the shape as it is.
+	Identity Transform = iota
+	// Rotate90 turns the shape a quarter turn clockwise.
+	Rotate90
+	// Rotate180 turns the shape half way round.
+	Rotate180
+	// Rotate270 turns the shape a quarter turn anticlockwise.
+	Rotate270
+	// ReflectHorizontal mirrors the shape in a horizontal line, reversing
+	// the order of its rows.
+	ReflectHorizontal
+	// ReflectDiagonal mirrors the shape in the diagonal from the upper
+	// left corner, swapping rows and columns.
+	ReflectDiagonal
+	// ReflectVertical mirrors the shape in a vertical line, reversing the
+	// order of its columns.
+	ReflectVertical
+	// ReflectAntiDiagonal mirrors the shape in the diagonal from the upper
+	// right corner.
+	ReflectAntiDiagonal
+)
+
+var transformNames = []string{"identity", "rotate 90", "rotate 180",
+	"rotate 270", "reflect horizontal", "reflect diagonal",
+	"reflect vertical", "reflect antidiagonal"}
+
+func (t Transform) String() string {
+	if t < Identity || t > ReflectAntiDiagonal {
+		return fmt.Sprintf("Transform(%d)", int(t))
+	}
+	return transformNames[t]
+}
+
+// Transform returns the Shape after the transform t.
+func (s Shape) Transform(t Transform) Shape {
+	if t >= ReflectHorizontal {
+		s = s.flip()
+	}
+	for i := 0; i < int(t)%4; i++ {
+		s = s.rotate()
+	}
+	return s
+}
+
+// Symmetry is a subgroup of D4, the set of transforms which map a shape
+// onto itself, with one bit for each Transform.
+type Symmetry uint8
+
+// symmetryNames names the ten subgroups of D4.  A subgroup with a single
+// reflection is named by its mirror line, and the two subgroups of order
+// four with reflections by whether their mirror lines are diagonal.
+var symmetryNames = []struct {
+	transforms []Transform
+	name       string
+}{
+	{[]Transform{Identity}, "C1"},
+	{[]Transform{Identity, Rotate180}, "C2"},
+	{[]Transform{Identity, Rotate90, Rotate180, Rotate270}, "C4"},
+	{[]Transform{Identity, ReflectHorizontal}, "D1 horizontal"},
+	{[]Transform{Identity, ReflectVertical}, "D1 vertical"},
+	{[]Transform{Identity, ReflectDiagonal}, "D1 diagonal"},
+	{[]Transform{Identity, ReflectAntiDiagonal}, "D1 antidiagonal"},
+	{[]Transform{Identity, Rotate180, ReflectHorizontal, ReflectVertical},
+		"D2 orthogonal"},
+	{[]Transform{Identity, Rotate180, ReflectDiagonal, ReflectAntiDiagonal},
+		"D2 diagonal"},
+	{[]Transform{Identity, Rotate90, Rotate180, Rotate270,
+		ReflectHorizontal, ReflectDiagonal, ReflectVertical,
+		ReflectAntiDiagonal}, "D4"},
+}
+
+func symmetryOf(transforms ...Transform) Symmetry {
+	sym := Symmetry(0)
+	for _, t := range transforms {
+		sym |= 1 << uint(t)
+	}
+	return sym
+}
+
+// Symmetry returns the transforms which leave the Shape's grid unchanged.
+// The number of distinct orientations of the shape, the length of
+// Permutations, is 8 divided by the order of its Symmetry.
+func (s Shape) Symmetry() Symmetry {
+	key := s.Key()
+	sym := Symmetry(0)
+	for t := Identity; t <= ReflectAntiDiagonal; t++ {
+		if s.Transform(t).Key() == key {
+			sym |= 1 << uint(t)
+		}
+	}
+	return sym
+}
+
+// Has returns true if the transform t is in the Symmetry.
+func (sym Symmetry) Has(t Transform) bool {
+	return sym&(1<<uint(t)) != 0
+}
+
+// Transforms lists the transforms in the Symmetry in order.
+func (sym Symmetry) Transforms() []Transform {
+	transforms := []Transform{}
+	for t := Identity; t <= ReflectAntiDiagonal; t++ {
+		if sym.Has(t) {
+			transforms = append(transforms, t)
+		}
+	}
+	return transforms
+}
+
+// Order returns the number of transforms in the Symmetry.
+func (sym Symmetry) Order() int {
+	return len(sym.Transforms())
+}
+
+// Chiral returns true if the Symmetry has no reflections, so the mirror
+// image of the shape is a different one-sided shape.
+func (sym Symmetry) Chiral() bool {
+	return sym&symmetryOf(ReflectHorizontal, ReflectDiagonal,
+		ReflectVertical, ReflectAntiDiagonal) == 0
+}
+
+// Name returns the usual name of the subgroup, such as "C4" or "D2
+// diagonal".
+func (sym Symmetry) Name() string {
+	for _, sn := range symmetryNames {
+		if symmetryOf(sn.transforms...) == sym {
+			return sn.name
+		}
+	}
+	names := []string{}
+	for _, t := range sym.Transforms() {
+		names = append(names, t.String())
+	}
+	return "{" + strings.Join(names, ", ") + "}"
+}
+
+func (sym Symmetry) String() string {
+	return sym.Name()
+}
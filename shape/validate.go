@@ -0,0 +1,114 @@
+// -*- tab-width: 4; -*-
+
+package shape
+
+import "fmt"
+
+// Connectivity selects which neighbouring cells connect the cells of a
+// shape into one piece.
+type Connectivity int
+
+const (
+	// Connect4 joins cells which share an edge.
+	Connect4 Connectivity = 4
+	// Connect8 joins cells which share an edge or a corner.
+	Connect8 Connectivity = 8
+)
+
+// NewValidShape is NewShape for grids which have not been checked, such
+// as those read from a file.  It returns an error instead of a Shape if
+// the grid fails Validate.
+func NewValidShape(id int, grid [][]int) (Shape, error) {
+	if err := validateGrid(grid, Connect4); err != nil {
+		return Shape{}, fmt.Errorf("shape #%d: %v", id, err)
+	}
+	return NewShape(id, grid), nil
+}
+
+// Validate returns an error if the Shape's grid is not a single piece
+// which fits in a mask: the rows must all be the same length, the grid can
+// be no bigger than 8x8, the values must be 0, 1 or 2 for a gap, and the
+// cells which are not 0 must be 4-connected.
+func (s Shape) Validate() error {
+	return s.ValidateConnectivity(Connect4)
+}
+
+// ValidateConnectivity is Validate with a choice of the neighbours which
+// connect cells, so that pieces joined only at corners can be allowed.
+func (s Shape) ValidateConnectivity(conn Connectivity) error {
+	if err := validateGrid(s.shape, conn); err != nil {
+		return fmt.Errorf("shape #%d: %v", s.id, err)
+	}
+	return nil
+}
+
+func validateGrid(grid [][]int, conn Connectivity) error {
+
+	if len(grid) == 0 || len(grid[0]) == 0 {
+		return fmt.Errorf("grid is empty")
+	}
+	ncol := len(grid[0])
+	if len(grid) > 8 || ncol > 8 {
+		return fmt.Errorf("grid is %dx%d, larger than 8x8", len(grid), ncol)
+	}
+	cells := 0
+	for r, row := range grid {
+		if len(row) != ncol {
+			return fmt.Errorf("row %d has %d cells, not %d", r+1, len(row),
+				ncol)
+		}
+		for c, v := range row {
+			if v < 0 || v > 2 {
+				return fmt.Errorf("cell (%d, %d) is %d, not 0, 1 or 2",
+					r, c, v)
+			}
+			if v != 0 {
+				cells++
+			}
+		}
+	}
+	if cells == 0 {
+		return fmt.Errorf("grid has no cells")
+	}
+	if n := connected(grid, conn); n != cells {
+		return fmt.Errorf("grid is not %d-connected: only %d of %d cells "+
+			"are joined to the first", int(conn), n, cells)
+	}
+	return nil
+}
+
+// connected counts the cells joined to the first cell of the grid.
+func connected(grid [][]int, conn Connectivity) int {
+
+	type cell struct{ r, c int }
+	seen := map[cell]bool{}
+	stack := []cell{}
+	for r := 0; r < len(grid) && len(stack) == 0; r++ {
+		for c := 0; c < len(grid[r]); c++ {
+			if grid[r][c] != 0 {
+				stack = append(stack, cell{r, c})
+				seen[cell{r, c}] = true
+				break
+			}
+		}
+	}
+	for len(stack) > 0 {
+		p := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for dr := -1; dr <= 1; dr++ {
+			for dc := -1; dc <= 1; dc++ {
+				if dr == 0 && dc == 0 || conn == Connect4 && dr != 0 && dc != 0 {
+					continue
+				}
+				n := cell{p.r + dr, p.c + dc}
+				if n.r < 0 || n.r >= len(grid) || n.c < 0 ||
+					n.c >= len(grid[n.r]) || grid[n.r][n.c] == 0 || seen[n] {
+					continue
+				}
+				seen[n] = true
+				stack = append(stack, n)
+			}
+		}
+	}
+	return len(seen)
+}
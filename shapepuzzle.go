@@ -4,79 +4,356 @@
 package main
 
 import (
+	"encoding/json"
+	"flag"
 	"fmt"
-	"log"
+	"log/slog"
+	"os"
 	"runtime"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/garyjg/shapepuzzle/board"
+	"github.com/garyjg/shapepuzzle/catalog"
+	"github.com/garyjg/shapepuzzle/puzzle"
 	"github.com/garyjg/shapepuzzle/shape"
 )
 
-// There are 8 permutations for every shape: flipped over or not, then
-// four rotations for each side up.
-
-// getShapes generates the shapes for a specific shape puzzle to be solved.
-//
-func getShapes() []shape.Shape {
-	grids := [][][]int{{
-		{1, 1, 0}, {1, 1, 1}}, {
-		{1, 0, 1}, {1, 1, 1}}, {
-		{1, 0, 0, 0, 0}, {1, 1, 1, 1, 1}}, {
-		{1, 1, 1, 1}, {1, 0, 0, 1}}, {
-		{1, 1, 1}, {1, 1, 1}, {0, 1, 1}}, {
-		{0, 1, 0}, {1, 1, 1}, {0, 1, 0}}, {
-		{0, 1, 0}, {0, 1, 0}, {1, 1, 1}}, {
-		{0, 0, 1, 1}, {1, 1, 1, 1}}, {
-		{0, 1, 1}, {1, 1, 0}, {1, 0, 0}}, {
-		{1, 0, 0, 0}, {1, 0, 0, 0}, {1, 0, 0, 0}, {1, 1, 1, 1}}, {
-		{1, 0, 0, 0}, {1, 1, 1, 1}, {1, 0, 0, 0}}}
-	return shape.MakeShapes(grids)
-}
-
-type _NullWriter struct {
-}
-
-func (nw _NullWriter) Write(b []byte) (n int, err error) {
-	return len(b), nil
+// newLogger returns a logger writing text records at or above the named
+// level to standard error.
+func newLogger(level string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("unknown log level: %s", level)
+	}
+	h := slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: lvl})
+	return slog.New(h), nil
 }
 
 func main() {
 
-	logenable := false
+	strategyName := flag.String("strategy", board.Pipeline.String(),
+		"search strategy: pipeline, firstcell or constrained, "+
+			"which solve NAME uses by default")
+	workers := flag.Int("workers", runtime.NumCPU(),
+		"number of goroutines sharing a firstcell or constrained search")
+	checkpointFile := flag.String("checkpoint", "",
+		"periodically save the search progress to this file")
+	interval := flag.Duration("checkpoint-interval", time.Minute,
+		"time between checkpoints")
+	resume := flag.Bool("resume", false,
+		"resume the search from the -checkpoint file")
+	shardName := flag.String("shard", "",
+		"search only shard i of K, given as i/K, with a cell strategy")
+	statsFormat := flag.String("stats", "",
+		"print search statistics at the end as a table or json")
+	showProgress := flag.Bool("progress", true,
+		"show a progress line on standard error if it is a terminal")
+	puzzleFile := flag.String("puzzle", "",
+		"solve the puzzle defined in this file instead of one from the catalog")
+	placementsFile := flag.String("placements", "",
+		"cache the placement table in this file, reading it if it exists")
+	optional := flag.Bool("optional", false,
+		"let any subset of the pieces fill the board, with a cell strategy")
+	logLevel := flag.String("log-level", "warn",
+		"log messages at this level or above: debug, info, warn or error")
+	flag.Parse()
 
-	runtime.GOMAXPROCS(8)
-	log.SetFlags(0)
+	name := catalog.Default
+	solving := false
+	switch flag.Arg(0) {
+	case "merge":
+		os.Exit(merge(flag.Args()[1:]))
+	case "list":
+		os.Exit(list())
+	case "cube":
+		os.Exit(solveCube(flag.Args()[1:]))
+	case "hex":
+		os.Exit(solveHex(flag.Args()[1:]))
+	case "tri":
+		os.Exit(solveTri(flag.Args()[1:]))
+	case "pack":
+		os.Exit(pack(flag.Args()[1:]))
+	case "generate":
+		os.Exit(generatePuzzle(flag.Args()[1:]))
+	case "rate":
+		os.Exit(rate(flag.Args()[1:]))
+	case "analyze":
+		os.Exit(analyze(flag.Args()[1:]))
+	case "play":
+		os.Exit(playPuzzle(flag.Args()[1:]))
+	case "solve":
+		// Flags may follow the puzzle name as well as come before it.
+		name = flag.Arg(1)
+		flag.CommandLine.Parse(flag.Args()[min(2, flag.NArg()):])
+		if name == "" || flag.NArg() != 0 || *puzzleFile != "" {
+			fmt.Fprintln(os.Stderr, "usage: shapepuzzle [flags] solve NAME [flags]")
+			os.Exit(2)
+		}
+		solving = true
+	case "":
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command: %s\n", flag.Arg(0))
+		os.Exit(2)
+	}
 
-	if !logenable {
-		log.SetOutput(_NullWriter{})
+	set := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	// A catalog puzzle is solved with a complete cell search unless asked
+	// otherwise, so that its count can be checked against the catalog.
+	if solving && !set["strategy"] {
+		*strategyName = board.ConstrainedCell.String()
+	}
+	strategy, err := board.ParseStrategy(*strategyName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if *optional && strategy == board.Pipeline {
+		fmt.Fprintln(os.Stderr, "optional pieces need a cell search strategy")
+		os.Exit(2)
+	}
+	var shard board.Shard
+	if *shardName != "" {
+		shard, err = board.ParseShard(*shardName)
+		if err == nil && strategy == board.Pipeline {
+			err = fmt.Errorf("shards need a cell search strategy")
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
 	}
 
-	b := board.NewBoard(8, 8)
-	shapes := getShapes()
+	logger, err := newLogger(*logLevel)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	slog.SetDefault(logger)
+
+	var p *puzzle.Puzzle
+	known := -1
+	if *puzzleFile != "" {
+		p, err = puzzle.Load(*puzzleFile)
+	} else if entry, ok := catalog.Lookup(name); ok {
+		if entry.Rect {
+			os.Exit(solveRect(entry, set, logger))
+		}
+		p, err = entry.Puzzle()
+		known = entry.Solutions
+	} else {
+		err = fmt.Errorf("unknown puzzle: %s", name)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	for _, dups := range p.Duplicates() {
+		ids := make([]int, len(dups))
+		for i, s := range dups {
+			ids[i] = s.ID()
+		}
+		logger.Warn("duplicate pieces give repeated solutions",
+			"name", dups[0].Name(), "ids", ids)
+	}
+	b, shapes := p.Board, p.Shapes
+	for _, s := range shapes {
+		if err := s.Validate(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
 
 	nshapes := len(shapes)
 	for i := 0; i < nshapes; i++ {
 		s := &shapes[i]
 		perms := s.Permutations()
+		logger.Info("shape", "id", s.ID(), "permutations", len(perms))
 		for _, p := range perms {
-			log.Printf(p.String())
+			logger.Debug("permutation", "id", s.ID(), "shape", p)
 		}
 	}
 
+	opts := board.Options{Strategy: strategy, Workers: *workers, Shard: shard,
+		Optional: *optional, Logger: logger}
+	if *statsFormat != "" {
+		if *statsFormat != "table" && *statsFormat != "json" {
+			fmt.Fprintf(os.Stderr, "unknown stats format: %s\n", *statsFormat)
+			os.Exit(2)
+		}
+		opts.Stats = &board.Stats{}
+	}
+	if *placementsFile != "" {
+		opts.Placements = loadPlacements(*placementsFile, b, shapes, logger)
+	}
+	nfound := 0
+	if *checkpointFile != "" {
+		opts.Checkpoint, err = loadCheckpoint(*checkpointFile, *resume,
+			b, shapes, opts)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		nfound = opts.Checkpoint.NumSolutions()
+		if nfound > 0 {
+			fmt.Printf("Resuming with %d solutions found before.\n", nfound)
+		}
+		stop := saveCheckpoints(opts.Checkpoint, *checkpointFile, *interval)
+		defer stop()
+	}
+
 	fmt.Printf("Initial board:\n%v", b)
 
-	bc := b.Solve(shapes)
-	nfound := 0
+	progress := newProgressLine(os.Stderr)
+	if *showProgress && progress.enabled {
+		opts.Observer = progress.update
+	}
+	subsets := &board.SubsetCounter{}
+	if opts.Checkpoint != nil {
+		subsets.AddCheckpoint(b, opts.Checkpoint)
+	}
+	bc, err := b.SolveOptions(shapes, opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 	for b := range bc {
+		progress.clear()
 		fmt.Printf("Solution found.\n")
 		fmt.Printf("%s\n", b)
+		subsets.Add(b)
 		nfound++
 	}
-	if nfound == 0 {
+	printCount(nfound)
+	if *optional {
+		printSubsets(subsets.Subsets(), shapes)
+	}
+	// Only a complete cell search finds every solution in the catalog.
+	if known >= 0 && nfound != known && strategy != board.Pipeline &&
+		shard.Count == 0 && !*optional {
+		logger.Warn("solution count differs from the catalog",
+			"puzzle", name, "found", nfound, "known", known)
+	}
+	if opts.Stats != nil {
+		printStats(opts.Stats, *statsFormat)
+	}
+}
+
+// printCount prints the number of solutions found, after the solutions.
+func printCount(n int) {
+	if n == 0 {
 		fmt.Printf("No solution found.\n")
-	} else if nfound == 1 {
+	} else if n == 1 {
 		fmt.Printf("One solution found.\n")
 	} else {
-		fmt.Printf("%d solutions found.\n", nfound)
+		fmt.Printf("%d solutions found.\n", n)
+	}
+}
+
+// printSubsets lists the subsets of pieces which fill the board, by name
+// where the pieces have names, with the number of solutions found using
+// each one.
+func printSubsets(subsets []board.Subset, shapes []shape.Shape) {
+	if len(subsets) == 0 {
+		return
+	}
+	names := map[int]string{}
+	for _, s := range shapes {
+		if s.Name() != "" {
+			names[s.ID()] = s.Name()
+		}
+	}
+	fmt.Printf("%d subsets of pieces fill the board:\n", len(subsets))
+	for _, s := range subsets {
+		pieces := make([]string, len(s.IDs))
+		for i, id := range s.IDs {
+			if pieces[i] = names[id]; pieces[i] == "" {
+				pieces[i] = strconv.Itoa(id)
+			}
+		}
+		fmt.Printf("%s: %d solutions\n", strings.Join(pieces, " "),
+			s.Solutions)
+	}
+}
+
+// printStats prints the search statistics as a table or as JSON.
+func printStats(stats *board.Stats, format string) {
+	if format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(stats)
+	} else {
+		stats.WriteTable(os.Stdout)
+	}
+}
+
+// loadPlacements reads the placement table cached at path, or builds the
+// table and saves it there if there is no table for this puzzle yet.
+func loadPlacements(path string, b board.Board, shapes []shape.Shape,
+	logger *slog.Logger) *board.PlacementTable {
+
+	t, err := board.ReadPlacementTable(path)
+	if err == nil {
+		if err = t.Verify(b, shapes); err == nil {
+			return t
+		}
+	}
+	if !os.IsNotExist(err) {
+		logger.Warn("rebuilding placement table", "path", path, "err", err)
+	}
+	t = board.NewPlacementTable(b, shapes)
+	if err := t.WriteFile(path); err != nil {
+		logger.Error("saving placement table failed", "path", path,
+			"err", err)
+	}
+	return t
+}
+
+// loadCheckpoint returns the checkpoint to resume from path, or a new
+// checkpoint if not resuming.
+func loadCheckpoint(path string, resume bool, b board.Board,
+	shapes []shape.Shape, opts board.Options) (*board.Checkpoint, error) {
+
+	if opts.Strategy == board.Pipeline {
+		return nil, fmt.Errorf("checkpoints need a cell search strategy")
+	}
+	if !resume {
+		return &board.Checkpoint{}, nil
+	}
+	cp, err := board.ReadCheckpoint(path)
+	if err != nil {
+		return nil, err
+	}
+	return cp, cp.Verify(b, shapes, opts)
+}
+
+// saveCheckpoints writes the checkpoint to path at every interval until
+// the returned function is called, which writes the final checkpoint.
+func saveCheckpoints(cp *board.Checkpoint, path string,
+	interval time.Duration) func() {
+
+	save := func() {
+		if err := cp.WriteFile(path); err != nil {
+			slog.Error("checkpoint failed", "path", path, "err", err)
+		}
+	}
+	ticker := time.NewTicker(interval)
+	done := make(chan bool)
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				save()
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		ticker.Stop()
+		close(done)
+		save()
 	}
 }
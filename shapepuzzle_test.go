@@ -5,6 +5,8 @@ package main
 import (
 	"fmt"
 	"log"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/garyjg/shapepuzzle/board"
@@ -47,3 +49,32 @@ func TestPlacements(t *testing.T) {
 	tb = tb.Place(shapes[1].Translate(1, 1))
 	log.Println(tb)
 }
+
+func TestReadSolutions(t *testing.T) {
+
+	text := "Initial board:\n[  0  0]\n[  0  0]\n" +
+		"Solution found.\n[  1  1]\n[  2  2]\n\n" +
+		"Solution found.\n[  1  2]\n[  1  2]\n\n2 solutions found.\n"
+	path := filepath.Join(t.TempDir(), "shard.out")
+	if err := os.WriteFile(path, []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+	solutions, err := readSolutions(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(solutions) != 2 || solutions[0] != "[  1  1]\n[  2  2]\n" {
+		t.Errorf("readSolutions() = %q", solutions)
+	}
+}
+
+func TestHumanCount(t *testing.T) {
+	tests := map[float64]string{
+		12: "12", 1234: "1.2k", 2500000: "2.5M", 3.25e9: "3.2G",
+	}
+	for n, want := range tests {
+		if got := humanCount(n); got != want {
+			t.Errorf("humanCount(%v) = %s, want %s", n, got, want)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+// -*- tab-width: 4; -*-
+
+package tri
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/garyjg/shapepuzzle/cover"
+)
+
+// MaxCells is the largest number of cells on a board, as many as the cover
+// search can fill.
+const MaxCells = cover.MaxCells
+
+// Placement is a shape at one position on a board, given by the shape's ID
+// and the set of the cells it covers.
+type Placement = cover.Placement[cover.Set]
+
+type filling = cover.Filling[cover.Set]
+
+// Board is a set of triangular cells, each given a cell of a cover.Set in
+// the order they were listed, with the shape placements made on it so far.
+// Copies of a Board share its cells.
+type Board struct {
+	filling
+	cells []Point
+	index map[Point]int
+}
+
+// NewBoard makes an empty board of the given cells.  It returns an error if
+// there are more than MaxCells of them or any is listed twice.
+func NewBoard(cells []Point) (Board, error) {
+	if len(cells) > MaxCells {
+		return Board{}, fmt.Errorf("board has %d cells, more than %d",
+			len(cells), MaxCells)
+	}
+	b := Board{cells: cells, index: map[Point]int{}}
+	for i, p := range cells {
+		if _, dup := b.index[p]; dup {
+			return Board{}, fmt.Errorf("board has cell %v twice", p)
+		}
+		b.index[p] = i
+	}
+	return b, nil
+}
+
+// NewTriangle makes a board in the shape of a triangle pointing up, with
+// side cells on each side and side*side cells in all.
+func NewTriangle(side int) (Board, error) {
+	if side < 1 {
+		return Board{}, fmt.Errorf("triangle side is %d", side)
+	}
+	// Start every row with an upward triangle.
+	s := (side - 1) % 2
+	cells := []Point{}
+	for r := 0; r < side; r++ {
+		for c := side - 1 - r; c <= side-1+r; c++ {
+			cells = append(cells, Point{r, c + s})
+		}
+	}
+	return NewBoard(cells)
+}
+
+// NewHexagon makes a board in the shape of a hexagon with side cells on
+// each side, and 6*side*side cells in all.  A side of 4 is the largest
+// which fits in a Set.
+func NewHexagon(side int) (Board, error) {
+	if side < 1 {
+		return Board{}, fmt.Errorf("hexagon side is %d", side)
+	}
+	// The rows of the upper half start and end with upward triangles, and
+	// those of the lower half with downward ones.
+	s := (side - 1) % 2
+	cells := []Point{}
+	for r := 0; r < 2*side; r++ {
+		start, width := side-1-r, 2*side+1+2*r
+		if r >= side {
+			start, width = r-side, 2*side+1+2*(2*side-1-r)
+		}
+		for c := start; c < start+width; c++ {
+			cells = append(cells, Point{r, c + s})
+		}
+	}
+	return NewBoard(cells)
+}
+
+// NewParallelogram makes a board of rows of cols pairs of triangles, each
+// row half a pair to the left of the one above, so that the upward
+// triangles which start the rows line up along the left side.
+func NewParallelogram(rows int, cols int) (Board, error) {
+	if rows < 1 || cols < 1 {
+		return Board{}, fmt.Errorf("parallelogram is %dx%d", rows, cols)
+	}
+	// Start every row with an upward triangle.
+	s := (rows - 1) % 2
+	cells := []Point{}
+	for r := 0; r < rows; r++ {
+		for c := rows - 1 - r; c < rows-1-r+2*cols; c++ {
+			cells = append(cells, Point{r, c + s})
+		}
+	}
+	return NewBoard(cells)
+}
+
+// Cells returns the cells of the board, in the order of their bits.
+func (b Board) Cells() []Point {
+	return b.cells
+}
+
+// Bit returns the Set of the cell p, which is empty if p is not on the
+// board.
+func (b Board) Bit(p Point) cover.Set {
+	i, ok := b.index[p]
+	if !ok {
+		return cover.Set{}
+	}
+	return cover.Cell(i)
+}
+
+// Region returns the Set of every cell of the board.
+func (b Board) Region() cover.Set {
+	return cover.FirstCells(len(b.cells))
+}
+
+// Place returns a copy of the Board with the placement added.  It does
+// not check that the placement fits.
+func (b Board) Place(p Placement) Board {
+	b.filling = b.filling.Place(p)
+	return b
+}
+
+// Neighbors returns the cells of the board which share an edge with any
+// cell of m and are not in m themselves.
+func (b Board) Neighbors(m cover.Set) cover.Set {
+	n := cover.Set{}
+	for rest := m; !rest.Empty(); {
+		i := rest.First()
+		rest = rest.AndNot(cover.Cell(i))
+		for _, nb := range b.cells[i].Neighbors() {
+			n = n.Or(b.Bit(nb))
+		}
+	}
+	return n.AndNot(m)
+}
+
+// placementsOf returns every orientation of the Shape translated to every
+// position on the Board which does not overlap the cells already filled.
+// A translation moves the first cell of the shape onto a board cell
+// pointing the same way.
+func (b Board) placementsOf(s Shape, mirror bool) []cover.Set {
+	places := []cover.Set{}
+	for _, o := range s.Orientations(mirror) {
+		first := o.cells[0]
+		for _, at := range b.cells {
+			if at.Up() != first.Up() {
+				continue
+			}
+			m := cover.Set{}
+			for _, p := range o.cells {
+				bit := b.Bit(Point{p.R + at.R - first.R, p.C + at.C - first.C})
+				if bit.Empty() || !bit.And(b.Mask()).Empty() {
+					m = cover.Set{}
+					break
+				}
+				m = m.Or(bit)
+			}
+			if !m.Empty() {
+				places = append(places, m)
+			}
+		}
+	}
+	return places
+}
+
+// Channel is a channel for passing Board states.
+type Channel chan Board
+
+// Solve searches for every way to fill the rest of the board with one
+// placement of each shape, and sends each solution on the channel it
+// returns, which is closed when the search is done.  Shapes are turned by
+// the six rotations of the grid, and flipped over as well if mirror is
+// true.  It returns an error, without searching, if there are more shapes
+// than the search can place.
+func (b Board) Solve(shapes []Shape, mirror bool) (Channel, error) {
+
+	p := &cover.Problem{Region: b.Region().AndNot(b.Mask()),
+		Neighbors: b.Neighbors}
+	for _, s := range shapes {
+		p.Pieces = append(p.Pieces, b.placementsOf(s, mirror))
+	}
+	bc, err := cover.Stream(p, func(choices []cover.Choice) Board {
+		nb := b
+		for _, ch := range choices {
+			nb = nb.Place(Placement{ID: shapes[ch.Piece].ID(), Mask: ch.Cells})
+		}
+		return nb
+	})
+	return bc, err
+}
+
+// String draws the Board a row at a time, with the ID of the shape in each
+// cell followed by ^ for an upward triangle or v for a downward one.
+func (b Board) String() string {
+	if len(b.cells) == 0 {
+		return ""
+	}
+	lo, hi := b.cells[0], b.cells[0]
+	for _, p := range b.cells {
+		lo = Point{min(lo.R, p.R), min(lo.C, p.C)}
+		hi = Point{max(hi.R, p.R), max(hi.C, p.C)}
+	}
+	buf := ""
+	for r := lo.R; r <= hi.R; r++ {
+		line := ""
+		for c := lo.C; c <= hi.C; c++ {
+			p := Point{r, c}
+			bit := b.Bit(p)
+			if bit.Empty() {
+				line += "    "
+				continue
+			}
+			id := b.ID(bit)
+			dir := "v"
+			if p.Up() {
+				dir = "^"
+			}
+			line += fmt.Sprintf(" %2d%s", id, dir)
+		}
+		buf += strings.TrimRight(line, " ") + "\n"
+	}
+	return buf
+}
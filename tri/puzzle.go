@@ -0,0 +1,167 @@
+// -*- tab-width: 4; -*-
+
+package tri
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/garyjg/shapepuzzle/puzzle"
+)
+
+// Puzzle is a board and the polyiamonds which must fill it.  Pieces may
+// be flipped over unless OneSided is true.
+//
+// A triangle puzzle file has the directives of the puzzle package, with
+// the board given as "board triangle SIDE", "board hexagon SIDE" or "board
+// parallelogram ROWS COLS", and pieces drawn a row of triangles at a time,
+// the first cell of the first row pointing up:
+//
+//	# A bar of four triangles and a triangle of four.
+//	name example
+//	board parallelogram 1 4
+//	piece A
+//	####
+//	piece B
+//	.###
+//	..#.
+//
+// The second row of piece B is the downward triangle below the middle of
+// the first.  A one-sided directive stops pieces from being flipped over.
+type Puzzle struct {
+	Name     string
+	Board    Board
+	Shapes   []Shape
+	OneSided bool
+}
+
+// Load reads the triangle puzzle definition in the file at path.
+func Load(path string) (*Puzzle, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	p, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return p, nil
+}
+
+// grammar describes triangle puzzle files to puzzle.ParseDefinition.
+var grammar = puzzle.Grammar[Board, Shape]{
+	Flags: []string{"one-sided"},
+	Board: parseBoard,
+	Piece: func(id int, grid []string) (Shape, error) {
+		return NewShape(id, puzzle.Cells(grid))
+	},
+	Name: Shape.WithName,
+}
+
+// Parse reads a triangle puzzle definition.  Errors give the line number of
+// the directive at fault.
+func Parse(r io.Reader) (*Puzzle, error) {
+	d, err := puzzle.ParseDefinition(r, grammar)
+	if err != nil {
+		return nil, err
+	}
+	return &Puzzle{Name: d.Name, Board: d.Board, Shapes: d.Shapes,
+		OneSided: d.Flags["one-sided"]}, nil
+}
+
+func parseBoard(args []string) (Board, error) {
+	sizes := []int{}
+	for _, arg := range args[min(1, len(args)):] {
+		n, err := strconv.Atoi(arg)
+		if err != nil {
+			return Board{}, fmt.Errorf("bad board size: %v", err)
+		}
+		sizes = append(sizes, n)
+	}
+	switch {
+	case len(args) == 2 && args[0] == "triangle":
+		return NewTriangle(sizes[0])
+	case len(args) == 2 && args[0] == "hexagon":
+		return NewHexagon(sizes[0])
+	case len(args) == 3 && args[0] == "parallelogram":
+		return NewParallelogram(sizes[0], sizes[1])
+	}
+	return Board{}, fmt.Errorf("board needs triangle SIDE, hexagon SIDE " +
+		"or parallelogram ROWS COLS")
+}
+
+// Iamonds is the three tetriamonds and four pentiamonds, which fill a
+// parallelogram of two rows of eight pairs of triangles in 4 ways, two
+// solutions and their half turns.
+const Iamonds = `
+name iamonds
+board parallelogram 2 8
+piece A
+####
+piece B
+###
+#..
+piece C
+.###
+..#.
+piece D
+#####
+piece E
+####
+#...
+piece F
+####
+..#.
+piece G
+###
+##.
+`
+
+// Hexiamonds is the twelve hexiamonds, the classic polyiamond set, which
+// fill a parallelogram of six rows of six pairs of triangles in 624 ways:
+// 156 solutions, each in the four symmetries of the board.
+const Hexiamonds = `
+name hexiamonds
+board parallelogram 6 6
+piece A
+######
+piece B
+#####
+#....
+piece C
+#####
+..#..
+piece D
+####
+##..
+piece E
+####
+#.#.
+piece F
+####
+.##.
+piece G
+####
+..##
+piece H
+###
+###
+piece I
+###..
+..###
+piece J
+#..
+##.
+###
+piece K
+#.
+##
+##
+#.
+piece L
+.###
+.###
+`
@@ -0,0 +1,322 @@
+// -*- tab-width: 4; -*-
+
+// Package tri solves polyiamond puzzles, on boards of triangular cells.
+//
+// Cells are numbered by row and column, each row alternating between
+// triangles pointing up and down, with the cell at row R and column C
+// pointing up when R+C is even.  An upward triangle shares its bottom
+// edge with the downward triangle below it, in the same column, and every
+// triangle shares its slanted edges with the cells to its left and right.
+// A translation must keep R+C even or odd, so shapes only move by an even
+// number of rows and columns together.
+//
+// A row of triangles is twice as long as a row of squares of the same
+// width, so a board numbers its own cells, up to 128 of them in a
+// cover.Set, instead of laying them out in the rows of a mask.
+package tri
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/garyjg/shapepuzzle/shape"
+)
+
+// Point is a cell of the triangular grid.
+type Point struct {
+	R, C int
+}
+
+// Up returns true if the triangle at p points up.
+func (p Point) Up() bool {
+	return (p.R+p.C)%2 == 0
+}
+
+// Neighbors returns the three cells which share an edge with p.
+func (p Point) Neighbors() [3]Point {
+	v := Point{p.R + 1, p.C}
+	if !p.Up() {
+		v = Point{p.R - 1, p.C}
+	}
+	return [3]Point{{p.R, p.C - 1}, {p.R, p.C + 1}, v}
+}
+
+// vertex is a corner of a triangle in the coordinates of the lattice,
+// along a row and along the slant down and to the right.
+type vertex struct {
+	i, j int
+}
+
+// corners returns the three corners of the triangle at p.
+func (p Point) corners() [3]vertex {
+	// Row j runs from the corners of row j to those of row j+1.  The
+	// downward triangle with its top left corner at (i, j) is in column
+	// 2i+j+1, and the upward triangle to its right in column 2i+j+2.
+	// Both divisions are exact, since R+C is even for an upward triangle
+	// and odd for a downward one.
+	j := p.R
+	if p.Up() {
+		i := (p.C - j - 2) / 2
+		return [3]vertex{{i + 1, j}, {i, j + 1}, {i + 1, j + 1}}
+	}
+	i := (p.C - j - 1) / 2
+	return [3]vertex{{i, j}, {i + 1, j}, {i, j + 1}}
+}
+
+// fromCorners returns the triangle with the given corners.
+func fromCorners(vs [3]vertex) Point {
+	lo := vs[0]
+	for _, v := range vs {
+		lo = vertex{min(lo.i, v.i), min(lo.j, v.j)}
+	}
+	for _, v := range vs {
+		if v == lo {
+			return Point{lo.j, 2*lo.i + lo.j + 1}
+		}
+	}
+	return Point{lo.j, 2*lo.i + lo.j + 2}
+}
+
+// Shape is a polyiamond: a connected set of triangles, moved as close to
+// the origin as it goes, with an ID and an optional name.
+type Shape struct {
+	id    int
+	name  string
+	cells []Point
+}
+
+// NewShape makes a Shape from rows of cells, where cells which are not 0
+// are part of the shape and the first cell of the first row points up.  It
+// returns an error if the shape is empty or not connected.
+func NewShape(id int, grid [][]int) (Shape, error) {
+	cells := []Point{}
+	for r, row := range grid {
+		for c, v := range row {
+			if v != 0 {
+				cells = append(cells, Point{r, c})
+			}
+		}
+	}
+	if len(cells) == 0 {
+		return Shape{}, fmt.Errorf("polyiamond #%d has no cells", id)
+	}
+	if !connected(cells) {
+		return Shape{}, fmt.Errorf("polyiamond #%d is not connected", id)
+	}
+	return Shape{id: id, cells: normalize(cells)}, nil
+}
+
+// connected returns true if every cell can be reached from every other
+// through the cells.
+func connected(cells []Point) bool {
+	in := map[Point]bool{}
+	for _, p := range cells {
+		in[p] = true
+	}
+	seen := map[Point]bool{cells[0]: true}
+	todo := []Point{cells[0]}
+	for len(todo) > 0 {
+		p := todo[len(todo)-1]
+		todo = todo[:len(todo)-1]
+		for _, n := range p.Neighbors() {
+			if in[n] && !seen[n] {
+				seen[n] = true
+				todo = append(todo, n)
+			}
+		}
+	}
+	return len(seen) == len(in)
+}
+
+// normalize moves the cells up to row 0 and left to column 0 or 1, by an
+// even number of rows and columns together, and sorts them.
+func normalize(cells []Point) []Point {
+	lo := cells[0]
+	for _, p := range cells {
+		lo = Point{min(lo.R, p.R), min(lo.C, p.C)}
+	}
+	if (lo.R+lo.C)%2 != 0 {
+		lo.C--
+	}
+	out := make([]Point, len(cells))
+	for i, p := range cells {
+		out[i] = Point{p.R - lo.R, p.C - lo.C}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].R != out[j].R {
+			return out[i].R < out[j].R
+		}
+		return out[i].C < out[j].C
+	})
+	return out
+}
+
+// key identifies the cells of a normalized Shape.
+func (s Shape) key() string {
+	return fmt.Sprint(s.cells)
+}
+
+// WithName returns a copy of the Shape with the given name.
+func (s Shape) WithName(name string) Shape {
+	s.name = name
+	return s
+}
+
+// ID returns the integer id of the Shape.
+func (s Shape) ID() int {
+	return s.id
+}
+
+// Name returns the name of the Shape, if it has one.
+func (s Shape) Name() string {
+	return s.name
+}
+
+// Cells returns the cells of the Shape.
+func (s Shape) Cells() []Point {
+	return s.cells
+}
+
+func (s Shape) String() string {
+	return fmt.Sprintf("Polyiamond #%d %s %v", s.id, s.name, s.cells)
+}
+
+// Transform is one of the twelve symmetries of the triangular grid: a
+// rotation by a multiple of 60 degrees, numbered 0 to 5, or a reflection
+// followed by one of those rotations, numbered 6 to 11.  A rotation by an
+// odd multiple of 60 degrees turns upward triangles into downward ones.
+type Transform int
+
+// Apply returns the cell p after the transform t, about a corner of the
+// lattice.
+func (t Transform) Apply(p Point) Point {
+	vs := p.corners()
+	for k, v := range vs {
+		if t >= 6 {
+			v = vertex{v.j, v.i}
+		}
+		for n := 0; n < int(t)%6; n++ {
+			// A sixth of a turn clockwise.
+			v = vertex{-v.j, v.i + v.j}
+		}
+		vs[k] = v
+	}
+	return fromCorners(vs)
+}
+
+// Transform returns the Shape after the transform t, moved back to the
+// origin.
+func (s Shape) Transform(t Transform) Shape {
+	cells := make([]Point, len(s.cells))
+	for i, p := range s.cells {
+		cells[i] = t.Apply(p)
+	}
+	s.cells = normalize(cells)
+	return s
+}
+
+// Orientations returns the distinct orientations of the Shape under the
+// six rotations, and the six reflections as well if mirror is true.
+func (s Shape) Orientations(mirror bool) []Shape {
+	n := Transform(6)
+	if mirror {
+		n = 12
+	}
+	seen := map[string]bool{}
+	shapes := []Shape{}
+	for t := Transform(0); t < n; t++ {
+		o := s.Transform(t)
+		if key := o.key(); !seen[key] {
+			seen[key] = true
+			shapes = append(shapes, o)
+		}
+	}
+	return shapes
+}
+
+// Canonical returns the orientation of the Shape which sorts first among
+// those the kind of transforms allows.
+func (s Shape) Canonical(kind shape.Kind) Shape {
+	if kind == shape.Fixed {
+		return s
+	}
+	best := s
+	for _, o := range s.Orientations(kind == shape.Free) {
+		if o.key() < best.key() {
+			best = o
+		}
+	}
+	return best
+}
+
+// MaxPolyiamond is the largest number of cells Polyiamonds generates.
+const MaxPolyiamond = 8
+
+// Polyiamonds generates every polyiamond of n cells, from 1 up to
+// MaxPolyiamond, with the given kind of transforms treated as the same
+// shape.  One-sided shapes follow the free shape they come from, and
+// fixed shapes list every orientation of each free shape.  IDs count from
+// 1 in the order returned.
+func Polyiamonds(n int, kind shape.Kind) ([]Shape, error) {
+
+	if n < 1 || n > MaxPolyiamond {
+		return nil, fmt.Errorf("polyiamonds need 1 to %d cells, not %d",
+			MaxPolyiamond, n)
+	}
+	if kind != shape.Free && kind != shape.OneSided && kind != shape.Fixed {
+		return nil, fmt.Errorf("unknown polyiamond kind: %v", kind)
+	}
+
+	// Every free polyiamond of n cells is one of n-1 cells with a
+	// neighboring cell added.
+	monoiamond := Shape{cells: []Point{{0, 0}}}
+	free := map[string]Shape{monoiamond.key(): monoiamond}
+	for size := 1; size < n; size++ {
+		next := map[string]Shape{}
+		for _, s := range free {
+			in := map[Point]bool{}
+			for _, p := range s.cells {
+				in[p] = true
+			}
+			for _, p := range s.cells {
+				for _, nb := range p.Neighbors() {
+					if in[nb] {
+						continue
+					}
+					cells := append(s.cells[:len(s.cells):len(s.cells)], nb)
+					g := Shape{cells: normalize(cells)}.Canonical(shape.Free)
+					next[g.key()] = g
+				}
+			}
+		}
+		free = next
+	}
+	keys := make([]string, 0, len(free))
+	for key := range free {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	shapes := []Shape{}
+	for _, key := range keys {
+		s := free[key]
+		var add []Shape
+		switch kind {
+		case shape.Free:
+			add = []Shape{s}
+		case shape.OneSided:
+			add = []Shape{s}
+			mirror := s.Transform(6).Canonical(shape.OneSided)
+			if mirror.key() != s.Canonical(shape.OneSided).key() {
+				add = append(add, mirror)
+			}
+		case shape.Fixed:
+			add = s.Orientations(true)
+		}
+		for _, a := range add {
+			a.id = len(shapes) + 1
+			shapes = append(shapes, a)
+		}
+	}
+	return shapes, nil
+}
@@ -0,0 +1,146 @@
+// -*- tab-width: 4; -*-
+
+package tri
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/garyjg/shapepuzzle/shape"
+)
+
+func TestPolyiamonds(t *testing.T) {
+	// The numbers of free, one-sided and fixed polyiamonds of 1 to 8
+	// cells.
+	expect := map[shape.Kind][]int{
+		shape.Free:     {1, 1, 1, 3, 4, 12, 24, 66},
+		shape.OneSided: {1, 1, 1, 4, 6, 19, 43, 120},
+		shape.Fixed:    {2, 3, 6, 14, 36, 94, 250, 675},
+	}
+	for kind, counts := range expect {
+		for n := 1; n <= MaxPolyiamond; n++ {
+			shapes, err := Polyiamonds(n, kind)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(shapes) != counts[n-1] {
+				t.Errorf("got %d %v polyiamonds of %d cells, expected %d",
+					len(shapes), kind, n, counts[n-1])
+			}
+		}
+	}
+}
+
+func TestTransform(t *testing.T) {
+	for _, p := range []Point{{0, 0}, {0, 1}, {3, -2}, {-1, -4}, {2, 5}} {
+		if got := fromCorners(p.corners()); got != p {
+			t.Errorf("corners of %v give %v", p, got)
+		}
+		if Transform(1).Apply(p).Up() == p.Up() {
+			t.Errorf("a sixth of a turn should flip %v", p)
+		}
+		if Transform(2).Apply(p).Up() != p.Up() {
+			t.Errorf("a third of a turn should not flip %v", p)
+		}
+		turned := p
+		for i := 0; i < 6; i++ {
+			turned = Transform(1).Apply(turned)
+		}
+		if turned != p {
+			t.Errorf("six turns moved %v to %v", p, turned)
+		}
+		if got := Transform(6).Apply(Transform(6).Apply(p)); got != p {
+			t.Errorf("two reflections moved %v to %v", p, got)
+		}
+	}
+}
+
+func TestBoards(t *testing.T) {
+	// Every cell of a board has from one to three neighbors.  The boards
+	// are made from the arguments of the board directive.
+	boards := map[string]int{"triangle 8": 64, "triangle 11": 121,
+		"hexagon 2": 24, "hexagon 4": 96, "parallelogram 4 8": 64,
+		"parallelogram 6 6": 72}
+	for args, cells := range boards {
+		b, err := parseBoard(strings.Fields(args))
+		if err != nil {
+			t.Errorf("%s: %v", args, err)
+			continue
+		}
+		if n := len(b.Cells()); n != cells {
+			t.Errorf("%s has %d cells, expected %d", args, n, cells)
+		}
+		for _, p := range b.Cells() {
+			if n := b.Neighbors(b.Bit(p)).Count(); n < 1 || n > 3 {
+				t.Errorf("%s cell %v has %d neighbors", args, p, n)
+			}
+		}
+	}
+	if _, err := NewHexagon(5); err == nil {
+		t.Errorf("board of 150 cells should be an error")
+	}
+}
+
+func countSolutions(t *testing.T, definition string) int {
+	p, err := Parse(strings.NewReader(definition))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bc, err := p.Board.Solve(p.Shapes, !p.OneSided)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	for b := range bc {
+		if b.Mask() != b.Region() {
+			t.Errorf("solution does not fill the board:\n%v", b)
+		}
+		n++
+	}
+	return n
+}
+
+func TestSolve(t *testing.T) {
+	// Two straight triamonds fill a hexagon of six triangles in three
+	// ways, and in either order.
+	hexagon := "board hexagon 1\npiece A\n###\npiece B\n###\n"
+	if n := countSolutions(t, hexagon); n != 6 {
+		t.Errorf("triamonds have %d solutions, expected 6", n)
+	}
+	if n := countSolutions(t, Iamonds); n != 4 {
+		t.Errorf("iamonds have %d solutions, expected 4", n)
+	}
+	if testing.Short() {
+		t.Skip("counting the hexiamond solutions takes seconds")
+	}
+	if n := countSolutions(t, Hexiamonds); n != 624 {
+		t.Errorf("hexiamonds have %d solutions, expected 624", n)
+	}
+}
+
+func TestString(t *testing.T) {
+	b, err := NewHexagon(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b = b.Place(Placement{ID: 4,
+		Mask: b.Bit(Point{0, 0}).Or(b.Bit(Point{0, 1}))})
+	expect := "  4^  4v  0^\n  0v  0^  0v\n"
+	if got := b.String(); got != expect {
+		t.Errorf("got board\n%s, expected\n%s", got, expect)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []string{
+		"board hexagon 5\npiece A\n#\n",
+		"board square 2\npiece A\n#\n",
+		"board parallelogram 2\npiece A\n#\n",
+		"board triangle 2\npiece A\n#.#\n",
+	}
+	for _, test := range tests {
+		if _, err := Parse(strings.NewReader(test)); err == nil {
+			t.Errorf("parsing %q should fail", test)
+		}
+	}
+}
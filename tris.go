@@ -0,0 +1,49 @@
+// -*- tab-width: 4; -*-
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/garyjg/shapepuzzle/tri"
+)
+
+// solveTri solves the tetriamonds and pentiamonds, the hexiamonds, or the
+// triangle puzzle defined in a file, and prints every solution a row at a
+// time.
+func solveTri(args []string) int {
+
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "usage: shapepuzzle tri "+
+			"iamonds|hexiamonds|FILE")
+		return 2
+	}
+	var p *tri.Puzzle
+	var err error
+	switch args[0] {
+	case "iamonds":
+		p, err = tri.Parse(strings.NewReader(tri.Iamonds))
+	case "hexiamonds":
+		p, err = tri.Parse(strings.NewReader(tri.Hexiamonds))
+	default:
+		p, err = tri.Load(args[0])
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	bc, err := p.Board.Solve(p.Shapes, !p.OneSided)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	nfound := 0
+	for b := range bc {
+		fmt.Printf("Solution found.\n%s\n", b)
+		nfound++
+	}
+	printCount(nfound)
+	return 0
+}